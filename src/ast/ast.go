@@ -10,6 +10,21 @@ import (
 type ASTNode interface{
 	TokenLiteral() string
 	String() string
+	GetSpan() Span
+	SetSpan(span Span)
+}
+
+// the source range a node was parsed from, set by the parser
+type Span struct{
+	Start token.Position
+	End token.Position
+}
+
+func (s Span) GetSpan() Span{ return s}
+func (s *Span) SetSpan(span Span){ *s = span}
+
+func TokenSpan(t token.Token) Span{
+	return Span{Start: t.Pos(), End: t.EndPos()}
 }
 
 type Statement interface{
@@ -23,7 +38,10 @@ type Expression interface{
 }
 
 type AstRootNode struct{
+	Span
 	Statements []Statement
+	// the text the program was parsed from, used to quote it in compiler errors
+	Source *token.Source
 }
 
 func (root *AstRootNode) TokenLiteral() string{
@@ -42,10 +60,15 @@ func (root *AstRootNode) String() string{
 	return out.String()
 }
 
+// a destructuring let binds through Pattern and leaves Variable nil
 type LetStatement struct{
 	Token token.Token
+	Span
 	Variable *Variable
+	Pattern Pattern
 	Value Expression
+	// export let, the name can be reached from a file that imports this one
+	Exported bool
 }
 func (ls *LetStatement) statementNode(){}
 func (ls *LetStatement) TokenLiteral() string{
@@ -53,8 +76,15 @@ func (ls *LetStatement) TokenLiteral() string{
 }
 func (ls *LetStatement) String() string{
 	var out bytes.Buffer
+	if ls.Exported{
+		out.WriteString("export ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Variable.String())
+	if ls.Pattern != nil{
+		out.WriteString(ls.Pattern.String())
+	}else{
+		out.WriteString(ls.Variable.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil{
 		out.WriteString(ls.Value.String())
@@ -63,8 +93,54 @@ func (ls *LetStatement) String() string{
 	return out.String()
 }
 
+// import "lib/math.dm" as m, the exports of the file are reached through m
+type ImportStatement struct{
+	Token token.Token
+	Span
+	Path *StringLiteral
+	Alias *Variable
+}
+
+func (is *ImportStatement) statementNode(){}
+func (is *ImportStatement) TokenLiteral() string{return is.Token.Identifier}
+func (is *ImportStatement) String() string{
+	return "import \"" + is.Path.Value + "\" as " + is.Alias.String() + ";"
+}
+
+// a, b = b, a, every value is evaluated before any target is assigned
+type ParallelAssignStatement struct{
+	Token token.Token
+	Span
+	Targets []Expression
+	Values []Expression
+}
+
+func (ps *ParallelAssignStatement) statementNode(){}
+func (ps *ParallelAssignStatement) TokenLiteral() string{return ps.Token.Identifier}
+func (ps *ParallelAssignStatement) String() string{
+	var out bytes.Buffer
+
+	targets := []string{}
+	for _, target := range ps.Targets{
+		targets = append(targets, target.String())
+	}
+
+	values := []string{}
+	for _, value := range ps.Values{
+		values = append(values, value.String())
+	}
+
+	out.WriteString(strings.Join(targets, ", "))
+	out.WriteString(" = ")
+	out.WriteString(strings.Join(values, ", "))
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type ReturnStatement struct{
 	Token token.Token
+	Span
 	Value Expression
 }
 
@@ -82,6 +158,7 @@ func (rs *ReturnStatement) String() string{
 
 type ExpressionStatement struct{
 	Token token.Token
+	Span
 	Expression Expression
 }
 
@@ -95,8 +172,80 @@ func (es *ExpressionStatement) String() string{
 	return ""
 }
 
+type WhileStatement struct{
+	Token token.Token
+	Span
+	Condition Expression
+	Body *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode(){}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Identifier}
+func (ws *WhileStatement) String() string{
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// every clause of the for header is optional, a missing condition loops until a break
+type ForStatement struct{
+	Token token.Token
+	Span
+	Init Statement
+	Condition Expression
+	Update Expression
+	Body *BlockStatement
+}
+
+func (fs *ForStatement) statementNode(){}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Identifier}
+func (fs *ForStatement) String() string{
+	var out bytes.Buffer
+
+	clauses := []string{"", "", ""}
+	if fs.Init != nil{
+		clauses[0] = strings.TrimSuffix(fs.Init.String(), ";")
+	}
+	if fs.Condition != nil{
+		clauses[1] = fs.Condition.String()
+	}
+	if fs.Update != nil{
+		clauses[2] = fs.Update.String()
+	}
+
+	out.WriteString("for(")
+	out.WriteString(strings.Join(clauses, ";"))
+	out.WriteString(")")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+type BreakStatement struct{
+	Token token.Token
+	Span
+}
+
+func (bs *BreakStatement) statementNode(){}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Identifier}
+func (bs *BreakStatement) String() string{ return bs.TokenLiteral() + ";"}
+
+type ContinueStatement struct{
+	Token token.Token
+	Span
+}
+
+func (cs *ContinueStatement) statementNode(){}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Identifier}
+func (cs *ContinueStatement) String() string{ return cs.TokenLiteral() + ";"}
+
 type BlockStatement struct{
 	Token token.Token
+	Span
 	Statements []Statement
 }
 
@@ -113,6 +262,7 @@ func (bs *BlockStatement) String() string{
 
 type Variable struct{
 	Token token.Token
+	Span
 	Value string
 }
 
@@ -122,6 +272,7 @@ func (v *Variable) String() string{ return v.Value}
 
 type IntegerLiteral struct{
 	Token token.Token
+	Span
 	Value int64
 }
 
@@ -129,8 +280,19 @@ func (il *IntegerLiteral) expressionNode(){}
 func (il *IntegerLiteral) TokenLiteral() string{return il.Token.Identifier}
 func (il *IntegerLiteral) String() string{return il.Token.Identifier}
 
+type FloatLiteral struct{
+	Token token.Token
+	Span
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode(){}
+func (fl *FloatLiteral) TokenLiteral() string{return fl.Token.Identifier}
+func (fl *FloatLiteral) String() string{return fl.Token.Identifier}
+
 type BooleanLiteral struct{
 	Token token.Token
+	Span
 	Value bool
 }
 
@@ -140,6 +302,7 @@ func (bl *BooleanLiteral) String() string{return bl.Token.Identifier}
 
 type StringLiteral struct{
 	Token token.Token
+	Span
 	Value string
 }
 
@@ -147,8 +310,34 @@ func (sl *StringLiteral) expressionNode(){}
 func (sl *StringLiteral) TokenLiteral() string{return sl.Token.Identifier}
 func (sl *StringLiteral) String() string{return sl.Token.Identifier}
 
+// "a ${x} b", the text parts are string literals and the rest are the expressions in between
+type InterpolatedString struct{
+	Token token.Token
+	Span
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode(){}
+func (is *InterpolatedString) TokenLiteral() string{return is.Token.Identifier}
+func (is *InterpolatedString) String() string{
+	var out bytes.Buffer
+	for _, part := range is.Parts{
+		if str, ok := part.(*StringLiteral); ok{
+			out.WriteString(str.Value)
+			continue
+		}
+
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
 type ArrayLiteral struct{
 	Token token.Token
+	Span
 	Elements []Expression
 }
 
@@ -170,6 +359,7 @@ func (al *ArrayLiteral) String() string{
 
 type IndexExpression struct{
 	Token token.Token
+	Span
 	Left Expression
 	Index Expression
 }
@@ -189,8 +379,52 @@ func (ie *IndexExpression) String() string{
   return out.String()
 }
 
+// m.name, the export name of the module m
+type MemberExpression struct{
+	Token token.Token
+	Span
+	Left Expression
+	Property *Variable
+}
+
+func (me *MemberExpression) expressionNode(){}
+func (me *MemberExpression) TokenLiteral() string{return me.Token.Identifier}
+func (me *MemberExpression) String() string{
+	return me.Left.String() + "." + me.Property.String()
+}
+
+// left[start:end], either bound can be left out
+type SliceExpression struct{
+	Token token.Token
+	Span
+	Left Expression
+	Start Expression
+	End Expression
+}
+
+func (se *SliceExpression) expressionNode(){}
+func (se *SliceExpression) TokenLiteral() string{ return se.Token.Identifier}
+func (se *SliceExpression) String() string{
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil{
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil{
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
 type HashLiteral struct{
 	Token token.Token
+	Span
 	Pairs map[Expression]Expression
 }
 
@@ -212,10 +446,13 @@ func (hl *HashLiteral) String() string{
 	return out.String()
 }
 
+// the else if branches are kept flat, in order, so a chain compiles to a single run of jumps
 type IfExpression struct{
 	Token token.Token
+	Span
 	Condition Expression
 	Consequence *BlockStatement
+	ElseIfs []*ElseIfBranch
 	Alternative *BlockStatement
 }
 
@@ -224,22 +461,55 @@ func (ie *IfExpression) TokenLiteral() string { return ie.Token.Identifier}
 func (ie *IfExpression) String() string{
 	var out bytes.Buffer
 
-	out.WriteString("if")
+	out.WriteString("if(")
 	out.WriteString(ie.Condition.String())
+	out.WriteString("){")
 	out.WriteString(ie.Consequence.String())
+	out.WriteString("}")
+
+	for _, branch := range ie.ElseIfs{
+		out.WriteString(branch.String())
+	}
 
 	if ie.Alternative != nil{
-		out.WriteString("else")
+		out.WriteString("else{")
 		out.WriteString(ie.Alternative.String())
+		out.WriteString("}")
 	}
 
 	return out.String()
 }
 
+type ElseIfBranch struct{
+	Token token.Token
+	Span
+	Condition Expression
+	Consequence *BlockStatement
+}
+
+func (eb *ElseIfBranch) TokenLiteral() string { return eb.Token.Identifier}
+func (eb *ElseIfBranch) String() string{
+	var out bytes.Buffer
+
+	out.WriteString("else if(")
+	out.WriteString(eb.Condition.String())
+	out.WriteString("){")
+	out.WriteString(eb.Consequence.String())
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Defaults lines up with Parameters and is nil for a parameter without one,
+// Rest collects the arguments past the parameters into an array
 type FunctionExpression struct{
 	Token token.Token
+	Span
 	Parameters []*Variable
+	Defaults []Expression
+	Rest *Variable
 	Body *BlockStatement
+	Name string
 }
 
 func (fe *FunctionExpression) expressionNode(){}
@@ -248,9 +518,16 @@ func (fe *FunctionExpression) String() string{
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, v := range fe.Parameters{
+	for i, v := range fe.Parameters{
+		if i < len(fe.Defaults) && fe.Defaults[i] != nil{
+			params = append(params, v.String()+"="+fe.Defaults[i].String())
+			continue
+		}
 		params = append(params, v.String())
 	}
+	if fe.Rest != nil{
+		params = append(params, "..."+fe.Rest.String())
+	}
 
 	out.WriteString("fn(")
 	out.WriteString(strings.Join(params,","))
@@ -261,8 +538,20 @@ func (fe *FunctionExpression) String() string{
 	return out.String()
 }
 
+// ...value in a call or an array literal, the elements of the array take its place
+type SpreadExpression struct{
+	Token token.Token
+	Span
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode(){}
+func (se *SpreadExpression) TokenLiteral() string{ return se.Token.Identifier}
+func (se *SpreadExpression) String() string{ return "..." + se.Value.String()}
+
 type CallExpression struct{
 	Token token.Token
+	Span
 	Function Expression
 	Arguments []Expression
 }
@@ -285,8 +574,52 @@ func (ce *CallExpression) String() string{
 	return out.String()
 }
 
+// plain assignment has the operator "=", the compound forms keep theirs like "+="
+type AssignExpression struct{
+	Token token.Token
+	Span
+	Name *Variable
+	Operator string
+	Value Expression
+}
+
+func (ae *AssignExpression) expressionNode(){}
+func (ae *AssignExpression) TokenLiteral() string{return ae.Token.Identifier}
+func (ae *AssignExpression) String() string{
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Name.String())
+	out.WriteString(ae.Operator)
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+type IndexAssignExpression struct{
+	Token token.Token
+	Span
+	Target *IndexExpression
+	Operator string
+	Value Expression
+}
+
+func (ia *IndexAssignExpression) expressionNode(){}
+func (ia *IndexAssignExpression) TokenLiteral() string{return ia.Token.Identifier}
+func (ia *IndexAssignExpression) String() string{
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ia.Target.String())
+	out.WriteString(ia.Operator)
+	out.WriteString(ia.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type PrefixExpression struct{
 	Token token.Token
+	Span
 	Operator string
 	Right Expression
 }
@@ -306,6 +639,7 @@ func (pe *PrefixExpression) String() string{
 
 type InfixExpression struct{
 	Token token.Token
+	Span
 	Left Expression
 	Operator string
 	Right Expression
@@ -322,4 +656,154 @@ func (ie *InfixExpression) String() string{
 	out.WriteString(")")
 
 	return out.String()
-}
\ No newline at end of file
+}
+
+type MatchExpression struct{
+	Token token.Token
+	Span
+	Subject Expression
+	Arms []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode(){}
+func (me *MatchExpression) TokenLiteral() string{return me.Token.Identifier}
+func (me *MatchExpression) String() string{
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms{
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match(")
+	out.WriteString(me.Subject.String())
+	out.WriteString("){")
+	out.WriteString(strings.Join(arms, ","))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// the guard is optional, the arm is only taken when it is truthy after the pattern matched
+type MatchArm struct{
+	Token token.Token
+	Span
+	Pattern Pattern
+	Guard Expression
+	Body Expression
+}
+
+func (ma *MatchArm) TokenLiteral() string{return ma.Token.Identifier}
+func (ma *MatchArm) String() string{
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil{
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// the shape a value is tested against, binding the parts it names
+type Pattern interface{
+	ASTNode
+	patternNode()
+}
+
+// _ matches anything and binds nothing
+type WildcardPattern struct{
+	Token token.Token
+	Span
+}
+
+func (wp *WildcardPattern) patternNode(){}
+func (wp *WildcardPattern) TokenLiteral() string{return wp.Token.Identifier}
+func (wp *WildcardPattern) String() string{return "_"}
+
+// a name matches anything and binds the value to it. in a destructuring let
+// the default is used instead when the value is missing or null
+type BindingPattern struct{
+	Token token.Token
+	Span
+	Name *Variable
+	Default Expression
+}
+
+func (bp *BindingPattern) patternNode(){}
+func (bp *BindingPattern) TokenLiteral() string{return bp.Token.Identifier}
+func (bp *BindingPattern) String() string{
+	if bp.Default != nil{
+		return bp.Name.String() + "=" + bp.Default.String()
+	}
+
+	return bp.Name.String()
+}
+
+// matches a value equal to the literal, a negative number is kept as a prefix expression
+type LiteralPattern struct{
+	Token token.Token
+	Span
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode(){}
+func (lp *LiteralPattern) TokenLiteral() string{return lp.Token.Identifier}
+func (lp *LiteralPattern) String() string{return lp.Value.String()}
+
+// without a rest the array has to have exactly as many elements as the pattern,
+// with one it needs at least as many and the rest collects the remaining ones
+type ArrayPattern struct{
+	Token token.Token
+	Span
+	Elements []Pattern
+	Rest Pattern
+}
+
+func (ap *ArrayPattern) patternNode(){}
+func (ap *ArrayPattern) TokenLiteral() string{return ap.Token.Identifier}
+func (ap *ArrayPattern) String() string{
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range ap.Elements{
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil{
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ","))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// the hash needs every key in the pattern, keys it doesn't name are ignored
+type HashPattern struct{
+	Token token.Token
+	Span
+	Keys []Expression
+	Values []Pattern
+}
+
+func (hp *HashPattern) patternNode(){}
+func (hp *HashPattern) TokenLiteral() string{return hp.Token.Identifier}
+func (hp *HashPattern) String() string{
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range hp.Keys{
+		pairs = append(pairs, key.String()+":"+hp.Values[i].String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ","))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -46,6 +46,8 @@ func (ins Instructions) instructionToFmt(def *Definition, operands []int) string
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
@@ -135,6 +137,42 @@ const (
 	OpSetLocal
 	OpGetLocal
 	OpGetBuiltin
+	OpClosure //wrap a compiled function constant and its free variables into a closure
+	OpGetFree //load a free variable captured by the current closure
+
+	OpCurrentClosure //load the closure that is currently executing, used for self reference
+	OpPatchFree      //overwrite a free variable of a closure, used for sibling functions that reference each other
+
+	OpSetIndex  //store a value into an array or hash in place, leaves the value on the stack
+	OpIndexKeep //like OpIndex but keeps the collection and index on the stack, used by compound index assignment
+
+	OpJumpNotTruthyOrPop //jump keeping the value when it is not truthy, otherwise pop it, used by &&
+	OpJumpTruthyOrPop    //jump keeping the value when it is truthy, otherwise pop it, used by ||
+
+	OpGreaterThanOrEqual
+	OpLessThanOrEqual
+
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpBitNot
+	OpShiftLeft
+	OpShiftRight
+
+	OpMatchArray //replace the value with whether it is an array of the given length, or at least that long when the flag is set
+	OpMatchHash  //replace the value with whether it is a hash
+	OpMatchKey   //pop a key and a hash, push whether the hash has the key
+	OpArrayRest  //replace an array with a new array of its elements from the given index on
+	OpMatchFail  //pop the value no match arm accepted and stop with an error
+
+	OpArrayExtend //pop a value and an array, push a new array with the elements of both
+	OpCallSpread  //call with the elements of the array on top of the stack as the arguments
+
+	OpSlice //pop the end, the start and an array or string, push the part between them, a null bound is left out
+
+	OpToString //replace the value on top of the stack with its string form, a string stays as it is
 )
 
 // not needed by the compiler, more useful for testing purposes to know how many operands the opcode has
@@ -167,6 +205,42 @@ var definitions = map[Opcode]*Definition{
 	OpSetLocal:      {"OpSetLocal", []int{1}},
 	OpGetLocal:      {"OpGetLocal", []int{1}},
 	OpGetBuiltin:    {"OpGetBuiltin", []int{1}},
+	OpClosure:       {"OpClosure", []int{2, 1}},
+	OpGetFree:       {"OpGetFree", []int{1}},
+
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpPatchFree:      {"OpPatchFree", []int{1}},
+
+	OpSetIndex:  {"OpSetIndex", []int{}},
+	OpIndexKeep: {"OpIndexKeep", []int{}},
+
+	OpJumpNotTruthyOrPop: {"OpJumpNotTruthyOrPop", []int{2}},
+	OpJumpTruthyOrPop:    {"OpJumpTruthyOrPop", []int{2}},
+
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+	OpLessThanOrEqual:    {"OpLessThanOrEqual", []int{}},
+
+	OpMod:        {"OpMod", []int{}},
+	OpPow:        {"OpPow", []int{}},
+	OpBitAnd:     {"OpBitAnd", []int{}},
+	OpBitOr:      {"OpBitOr", []int{}},
+	OpBitXor:     {"OpBitXor", []int{}},
+	OpBitNot:     {"OpBitNot", []int{}},
+	OpShiftLeft:  {"OpShiftLeft", []int{}},
+	OpShiftRight: {"OpShiftRight", []int{}},
+
+	OpMatchArray: {"OpMatchArray", []int{2, 1}},
+	OpMatchHash:  {"OpMatchHash", []int{}},
+	OpMatchKey:   {"OpMatchKey", []int{}},
+	OpArrayRest:  {"OpArrayRest", []int{2}},
+	OpMatchFail:  {"OpMatchFail", []int{}},
+
+	OpArrayExtend: {"OpArrayExtend", []int{}},
+	OpCallSpread:  {"OpCallSpread", []int{}},
+
+	OpSlice: {"OpSlice", []int{}},
+
+	OpToString: {"OpToString", []int{}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -14,6 +14,8 @@ func TestMakeBytecode(t *testing.T){
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
 		{OpPop, []int{}, []byte{byte(OpPop)}},
 		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+		{OpMatchArray, []int{3, 1}, []byte{byte(OpMatchArray), 0, 3, 1}},
 	}
 
 	for _, tt := range tests{
@@ -39,6 +41,7 @@ func TestInstructionsString(t *testing.T){
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
 		Make(OpPop),
+		Make(OpClosure, 65535, 255),
 	}
 
 	expected := `0000 OpAdd
@@ -46,6 +49,7 @@ func TestInstructionsString(t *testing.T){
 	0004 OpConstant 2
 	0007 OpConstant 65535
 	0010 OpPop
+	0011 OpClosure 65535 255
 	`
 
 	concatted := Instructions{}
@@ -68,6 +72,7 @@ func TestReadOperands(t *testing.T){
 	}{
 		{OpConstant, []int{65535}, 2},
 		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
 	}
 
 	for _, tt := range tests{
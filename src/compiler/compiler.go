@@ -7,6 +7,7 @@ import (
 	"github.com/singlaanish56/Compiler-in-go/ast"
 	"github.com/singlaanish56/Compiler-in-go/code"
 	"github.com/singlaanish56/Compiler-in-go/object"
+	"github.com/singlaanish56/Compiler-in-go/token"
 )
 
 type Compiler struct {
@@ -14,6 +15,10 @@ type Compiler struct {
 	compilerScopes []CompilationScope
 	scopeIndex     int
 	symbolTable    *SymbolTable
+	// quoted in errors, nil when the program was not parsed from source
+	source *token.Source
+	// the files imported so far, an import that was compiled before is not compiled again
+	modules *Modules
 }
 
 type Bytecode struct {
@@ -30,6 +35,14 @@ type CompilationScope struct {
 	instructions        code.Instructions
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+	loops               []*LoopScope
+}
+
+// jumps emitted by break and continue, patched once the loop is compiled.
+// a nil loop scope marks an expression boundary that they can't jump out of
+type LoopScope struct {
+	breakPositions    []int
+	continuePositions []int
 }
 
 func New() *Compiler {
@@ -49,6 +62,7 @@ func New() *Compiler {
 		compilerScopes: []CompilationScope{mainScope},
 		scopeIndex:     0,
 		symbolTable:    symbolTable,
+		modules:        NewModules(),
 	}
 }
 
@@ -59,85 +73,163 @@ func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
 	return compiler
 }
 
+// shares the modules with other compilations, a module imported by one
+// of them is not compiled again by the next
+func (c *Compiler) UseModules(modules *Modules) {
+	c.modules = modules
+}
+
 func (c *Compiler) Compile(node ast.ASTNode) error {
 	switch node := node.(type) {
 	case *ast.AstRootNode:
-		for _, statement := range node.Statements {
-			err := c.Compile(statement)
-			if err != nil {
-				return err
-			}
+		c.source = node.Source
+		err := c.compileStatements(node.Statements)
+		if err != nil {
+			return err
 		}
 	case *ast.LetStatement:
 		err := c.Compile(node.Value)
 		if err != nil {
 			return err
 		}
+
+		if node.Pattern != nil {
+			return c.compileDestructuring(node.Pattern)
+		}
+
 		symbol := c.symbolTable.Define(node.Variable.Value)
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetGlobal, symbol.Position)
-		} else {
-			c.emit(code.OpSetLocal, symbol.Position)
+		c.storeSymbol(symbol)
+	case *ast.ParallelAssignStatement:
+		err := c.compileParallelAssign(node)
+		if err != nil {
+			return err
 		}
-	case *ast.BlockStatement:
-		for _, s := range node.Statements {
-			err := c.Compile(s)
-			if err != nil {
-				return err
-			}
+	case *ast.ImportStatement:
+		err := c.compileImport(node)
+		if err != nil {
+			return err
 		}
-	case *ast.ExpressionStatement:
-		err := c.Compile(node.Expression)
+	case *ast.BlockStatement:
+		err := c.compileStatements(node.Statements)
 		if err != nil {
 			return err
 		}
-		c.emit(code.OpPop)
-	case *ast.ReturnStatement:
-		err := c.Compile(node.Value)
+	case *ast.ExpressionStatement:
+		var err error
+		if ifExp, ok := node.Expression.(*ast.IfExpression); ok {
+			err = c.compileIfExpression(ifExp)
+		} else {
+			err = c.Compile(node.Expression)
+		}
 		if err != nil {
 			return err
 		}
+		c.emit(code.OpPop)
+	case *ast.WhileStatement:
+		loopStart := len(c.currentInstructions())
 
-		c.emit(code.OpReturnValue)
-	case *ast.IfExpression:
 		err := c.Compile(node.Condition)
 		if err != nil {
 			return err
 		}
 
-		//dummy code to jump to
-		jumpNotTruthyPosition := c.emit(code.OpJumpNotTruthy, 9999)
+		exitJumpPos := c.emit(code.OpJumpNotTruthy, 9999)
 
-		err = c.Compile(node.Consequence)
+		c.enterLoop(&LoopScope{})
+		err = c.Compile(node.Body)
 		if err != nil {
 			return err
 		}
+		loop := c.leaveLoop()
 
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
+		c.emit(code.OpJump, loopStart)
+
+		afterLoopPos := len(c.currentInstructions())
+		c.changeOperand(exitJumpPos, afterLoopPos)
+		c.patchLoopJumps(loop, loopStart, afterLoopPos)
+	case *ast.ForStatement:
+		if node.Init != nil {
+			err := c.Compile(node.Init)
+			if err != nil {
+				return err
+			}
 		}
 
-		jumpPos := c.emit(code.OpJump, 9999)
+		loopStart := len(c.currentInstructions())
 
-		afterConsequencePos := len(c.currentInstructions())
-		c.changeOperand(jumpNotTruthyPosition, afterConsequencePos)
+		exitJumpPos := -1
+		if node.Condition != nil {
+			err := c.Compile(node.Condition)
+			if err != nil {
+				return err
+			}
 
-		if node.Alternative == nil {
-			c.emit(code.OpNull)
-		} else {
+			exitJumpPos = c.emit(code.OpJumpNotTruthy, 9999)
+		}
+
+		c.enterLoop(&LoopScope{})
+		err := c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+		loop := c.leaveLoop()
 
-			err := c.Compile(node.Alternative)
+		updatePos := len(c.currentInstructions())
+		if node.Update != nil {
+			err := c.Compile(node.Update)
 			if err != nil {
 				return err
 			}
+			c.emit(code.OpPop)
+		}
 
-			if c.lastInstructionIs(code.OpPop) {
-				c.removeLastPop()
-			}
+		c.emit(code.OpJump, loopStart)
+
+		afterLoopPos := len(c.currentInstructions())
+		if exitJumpPos != -1 {
+			c.changeOperand(exitJumpPos, afterLoopPos)
+		}
+		c.patchLoopJumps(loop, updatePos, afterLoopPos)
+	case *ast.BreakStatement:
+		loop, err := c.currentLoop(node)
+		if err != nil {
+			return err
+		}
+
+		loop.breakPositions = append(loop.breakPositions, c.emit(code.OpJump, 9999))
+	case *ast.ContinueStatement:
+		loop, err := c.currentLoop(node)
+		if err != nil {
+			return err
+		}
+
+		loop.continuePositions = append(loop.continuePositions, c.emit(code.OpJump, 9999))
+	case *ast.ReturnStatement:
+		if node.Value == nil {
+			c.emit(code.OpReturn)
+			break
+		}
+
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
 		}
 
-		alternativePos := len(c.currentInstructions())
-		c.changeOperand(jumpPos, alternativePos)
+		c.emit(code.OpReturnValue)
+	case *ast.IfExpression:
+		// the operands around an if used as a value are still on the stack
+		// while its blocks run, so break and continue can't jump out of them
+		c.enterLoop(nil)
+		err := c.compileIfExpression(node)
+		c.leaveLoop()
+		if err != nil {
+			return err
+		}
+	case *ast.MatchExpression:
+		err := c.compileMatchExpression(node)
+		if err != nil {
+			return err
+		}
 	case *ast.PrefixExpression:
 		err := c.Compile(node.Right)
 		if err != nil {
@@ -149,10 +241,18 @@ func (c *Compiler) Compile(node ast.ASTNode) error {
 			c.emit(code.OpBang)
 		case "-":
 			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
+		case "+":
+			// unary plus leaves the value as it is
 		default:
-			fmt.Errorf("unknown prefix operator %s", node.Operator)
+			return c.errorAt(node, "unknown prefix operator %s", node.Operator)
 		}
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
+
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
@@ -172,17 +272,84 @@ func (c *Compiler) Compile(node ast.ASTNode) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "**":
+			c.emit(code.OpPow)
+		case "&":
+			c.emit(code.OpBitAnd)
+		case "|":
+			c.emit(code.OpBitOr)
+		case "^":
+			c.emit(code.OpBitXor)
+		case "<<":
+			c.emit(code.OpShiftLeft)
+		case ">>":
+			c.emit(code.OpShiftRight)
 		case ">":
 			c.emit(code.OpGreaterThan)
 		case "<":
 			c.emit(code.OpLessThan)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
 			c.emit(code.OpNotEqual)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return c.errorAt(node, "unknown operator %s", node.Operator)
+		}
+	case *ast.AssignExpression:
+		symbol, err := c.resolveAssignable(node.Name)
+		if err != nil {
+			return err
+		}
+
+		if node.Operator != "=" {
+			c.loadSymbol(symbol)
+		}
+
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		err = c.emitAssignOperator(node, node.Operator)
+		if err != nil {
+			return err
+		}
+
+		// the assignment is an expression, its value is the one just stored
+		c.storeSymbol(symbol)
+		c.loadSymbol(symbol)
+	case *ast.IndexAssignExpression:
+		err := c.Compile(node.Target.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Target.Index)
+		if err != nil {
+			return err
+		}
+
+		if node.Operator != "=" {
+			c.emit(code.OpIndexKeep)
 		}
+
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		err = c.emitAssignOperator(node, node.Operator)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpSetIndex)
 	case *ast.IndexExpression:
 		err := c.Compile(node.Left)
 		if err != nil {
@@ -195,39 +362,56 @@ func (c *Compiler) Compile(node ast.ASTNode) error {
 		}
 
 		c.emit(code.OpIndex)
-	case *ast.FunctionExpression:
-		c.enterScope()
-
-		for _, param := range node.Parameters {
-			c.symbolTable.Define(param.Value)
-		}
-
-		err := c.Compile(node.Body)
+	case *ast.SliceExpression:
+		err := c.Compile(node.Left)
 		if err != nil {
 			return err
 		}
 
-		if c.lastInstructionIs(code.OpPop) {
-			c.replaceLastPopWithReturn()
+		for _, bound := range []ast.Expression{node.Start, node.End} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+
+			err := c.Compile(bound)
+			if err != nil {
+				return err
+			}
 		}
 
-		if !c.lastInstructionIs(code.OpReturnValue) {
-			c.emit(code.OpReturn)
+		c.emit(code.OpSlice)
+	case *ast.MemberExpression:
+		err := c.compileMemberExpression(node)
+		if err != nil {
+			return err
 		}
-		numLocals := c.symbolTable.numDefinitions
-		instructions := c.leaveScope()
-		compiledFn := &object.CompiledFunction{
-			Instructions:       instructions,
-			NumberOfLocals:     numLocals,
-			NumberOfParameters: len(node.Parameters),
+	case *ast.InterpolatedString:
+		err := c.compileInterpolatedString(node)
+		if err != nil {
+			return err
+		}
+	case *ast.FunctionExpression:
+		_, err := c.compileFunction(node)
+		if err != nil {
+			return err
 		}
-		c.emit(code.OpConstant, c.addConstant(compiledFn))
 	case *ast.CallExpression:
 		err := c.Compile(node.Function)
 		if err != nil {
 			return err
 		}
 
+		if hasSpread(node.Arguments) {
+			err := c.compileSpreadList(node.Arguments)
+			if err != nil {
+				return err
+			}
+
+			c.emit(code.OpCallSpread)
+			break
+		}
+
 		for _, arg := range node.Arguments {
 			err := c.Compile(arg)
 			if err != nil {
@@ -239,7 +423,10 @@ func (c *Compiler) Compile(node ast.ASTNode) error {
 	case *ast.Variable:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
-			return fmt.Errorf("undefined variable %s", node.Value)
+			return c.errorAt(node, "undefined variable %s", node.Value)
+		}
+		if symbol.Scope == ModuleScope {
+			return c.errorAt(node, "module %s can only be used to reach its exports, like %s.name", node.Value, node.Value)
 		}
 
 		c.loadSymbol(symbol)
@@ -247,6 +434,9 @@ func (c *Compiler) Compile(node ast.ASTNode) error {
 	case *ast.IntegerLiteral:
 		integerObject := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integerObject))
+	case *ast.FloatLiteral:
+		floatObject := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(floatObject))
 	case *ast.BooleanLiteral:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -280,7 +470,13 @@ func (c *Compiler) Compile(node ast.ASTNode) error {
 		}
 
 		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.SpreadExpression:
+		return c.errorAt(node, "... can only be used in a call or an array literal")
 	case *ast.ArrayLiteral:
+		if hasSpread(node.Elements) {
+			return c.compileSpreadList(node.Elements)
+		}
+
 		for _, element := range node.Elements {
 			err := c.Compile(element)
 			if err != nil {
@@ -293,6 +489,592 @@ func (c *Compiler) Compile(node ast.ASTNode) error {
 	return nil
 }
 
+// compiles a list of statements, consecutive let statements binding functions are
+// compiled together so the functions can reference each other in any order
+func (c *Compiler) compileStatements(statements []ast.Statement) error {
+	// every function let of the block is declared before anything is compiled, so
+	// functions can refer to each other whatever statements sit between them. a
+	// name declared twice is only declared ahead for its first function
+	declared := make(map[*ast.LetStatement]Symbol)
+	names := make(map[string]bool)
+	for _, statement := range statements {
+		let, ok := functionLet(statement)
+		if !ok || names[let.Variable.Value] {
+			continue
+		}
+
+		names[let.Variable.Value] = true
+		declared[let] = c.symbolTable.Define(let.Variable.Value)
+	}
+
+	// the functions assigned so far and the free variables each one captured
+	assigned := []Symbol{}
+	assignedFree := [][]Symbol{}
+	for _, statement := range statements {
+		let, _ := functionLet(statement)
+		symbol, ok := declared[let]
+		if !ok {
+			err := c.Compile(statement)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		free, err := c.compileFunction(let.Value.(*ast.FunctionExpression))
+		if err != nil {
+			return err
+		}
+		c.storeSymbol(symbol)
+
+		// a closure captures its free variables when it is created, so one created
+		// before this function was assigned captured an empty slot and is patched
+		for i, earlier := range assigned {
+			for freeIndex, captured := range assignedFree[i] {
+				if captured != symbol {
+					continue
+				}
+
+				c.loadSymbol(earlier)
+				c.loadSymbol(symbol)
+				c.emit(code.OpPatchFree, freeIndex)
+			}
+		}
+
+		assigned = append(assigned, symbol)
+		assignedFree = append(assignedFree, free)
+	}
+
+	return nil
+}
+
+// let name = fn(){ ... }
+func functionLet(statement ast.Statement) (*ast.LetStatement, bool) {
+	let, ok := statement.(*ast.LetStatement)
+	if !ok || let.Variable == nil {
+		return nil, false
+	}
+
+	_, ok = let.Value.(*ast.FunctionExpression)
+	return let, ok
+}
+
+// the symbol a variable can be assigned through, only names of the current
+// function or globals can be
+func (c *Compiler) resolveAssignable(name *ast.Variable) (Symbol, error) {
+	symbol, ok := c.symbolTable.Resolve(name.Value)
+	if !ok {
+		return symbol, c.errorAt(name, "cannot assign to undefined variable %s", name.Value)
+	}
+
+	switch symbol.Scope {
+	case BuiltinScope:
+		return symbol, c.errorAt(name, "cannot assign to builtin %s", name.Value)
+	case FreeScope, FunctionScope:
+		return symbol, c.errorAt(name, "cannot assign to %s, it belongs to an enclosing function", name.Value)
+	case ModuleScope:
+		return symbol, c.errorAt(name, "cannot assign to module %s", name.Value)
+	}
+
+	return symbol, nil
+}
+
+// the values go into hidden slots first so a target assigned early can't
+// change a value that is read later, a, b = b, a swaps them
+func (c *Compiler) compileParallelAssign(node *ast.ParallelAssignStatement) error {
+	temporaries := make([]Symbol, len(node.Values))
+	for i, value := range node.Values {
+		err := c.Compile(value)
+		if err != nil {
+			return err
+		}
+
+		temporaries[i] = c.symbolTable.defineHidden()
+		c.storeSymbol(temporaries[i])
+	}
+
+	for i, target := range node.Targets {
+		switch target := target.(type) {
+		case *ast.Variable:
+			symbol, err := c.resolveAssignable(target)
+			if err != nil {
+				return err
+			}
+
+			c.loadSymbol(temporaries[i])
+			c.storeSymbol(symbol)
+		case *ast.IndexExpression:
+			err := c.Compile(target.Left)
+			if err != nil {
+				return err
+			}
+
+			err = c.Compile(target.Index)
+			if err != nil {
+				return err
+			}
+
+			c.loadSymbol(temporaries[i])
+			c.emit(code.OpSetIndex)
+			c.emit(code.OpPop)
+		default:
+			return c.errorAt(target, "cannot assign to %s", target.String())
+		}
+	}
+
+	return nil
+}
+
+// binds the parts of the value on top of the stack and consumes it. unlike a
+// match nothing is checked, a missing element or key reads as null
+func (c *Compiler) compileDestructuring(pattern ast.Pattern) error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		c.emit(code.OpPop)
+	case *ast.BindingPattern:
+		symbol := c.symbolTable.Define(pattern.Name.Value)
+		c.storeSymbol(symbol)
+
+		if pattern.Default != nil {
+			c.loadSymbol(symbol)
+			c.emit(code.OpNull)
+			c.emit(code.OpEqual)
+			skipDefaultPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+			err := c.Compile(pattern.Default)
+			if err != nil {
+				return err
+			}
+			c.storeSymbol(symbol)
+
+			c.changeOperand(skipDefaultPos, len(c.currentInstructions()))
+		}
+	case *ast.ArrayPattern:
+		array := c.symbolTable.defineHidden()
+		c.storeSymbol(array)
+
+		for i, element := range pattern.Elements {
+			c.loadSymbol(array)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(i)}))
+			c.emit(code.OpIndex)
+
+			err := c.compileDestructuring(element)
+			if err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil {
+			c.loadSymbol(array)
+			c.emit(code.OpArrayRest, len(pattern.Elements))
+
+			err := c.compileDestructuring(pattern.Rest)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.HashPattern:
+		hash := c.symbolTable.defineHidden()
+		c.storeSymbol(hash)
+
+		for i, key := range pattern.Keys {
+			c.loadSymbol(hash)
+			err := c.Compile(key)
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpIndex)
+
+			err = c.compileDestructuring(pattern.Values[i])
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return c.errorAt(pattern, "cannot destructure into %s", pattern.String())
+	}
+
+	return nil
+}
+
+// emits the arithmetic half of a compound assignment, nothing for a plain one
+func (c *Compiler) emitAssignOperator(node ast.ASTNode, operator string) error {
+	switch operator {
+	case "=":
+	case "+=":
+		c.emit(code.OpAdd)
+	case "-=":
+		c.emit(code.OpSub)
+	case "*=":
+		c.emit(code.OpMul)
+	case "/=":
+		c.emit(code.OpDiv)
+	default:
+		return c.errorAt(node, "unknown assignment operator %s", operator)
+	}
+
+	return nil
+}
+
+// the right side only runs when the left one doesn't decide the result,
+// the value of the expression is whichever operand decided it
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	var jumpPos int
+	if node.Operator == "&&" {
+		jumpPos = c.emit(code.OpJumpNotTruthyOrPop, 9999)
+	} else {
+		jumpPos = c.emit(code.OpJumpTruthyOrPop, 9999)
+	}
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// every branch of an else if chain falls through to the next condition and
+// jumps straight to the end of the whole chain once its block has run
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	conditions := []ast.Expression{node.Condition}
+	consequences := []*ast.BlockStatement{node.Consequence}
+	for _, branch := range node.ElseIfs {
+		conditions = append(conditions, branch.Condition)
+		consequences = append(consequences, branch.Consequence)
+	}
+
+	endJumpPositions := []int{}
+	for i, condition := range conditions {
+		err := c.Compile(condition)
+		if err != nil {
+			return err
+		}
+
+		//dummy code to jump to
+		jumpNotTruthyPosition := c.emit(code.OpJumpNotTruthy, 9999)
+
+		err = c.compileBlockValue(consequences[i])
+		if err != nil {
+			return err
+		}
+
+		endJumpPositions = append(endJumpPositions, c.emit(code.OpJump, 9999))
+
+		afterConsequencePos := len(c.currentInstructions())
+		c.changeOperand(jumpNotTruthyPosition, afterConsequencePos)
+	}
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else {
+		err := c.compileBlockValue(node.Alternative)
+		if err != nil {
+			return err
+		}
+	}
+
+	afterChainPos := len(c.currentInstructions())
+	for _, pos := range endJumpPositions {
+		c.changeOperand(pos, afterChainPos)
+	}
+
+	return nil
+}
+
+// the subject is kept in a hidden slot and every arm tests it from scratch,
+// a failed test jumps to the next arm and falling past the last one is an error
+func (c *Compiler) compileMatchExpression(node *ast.MatchExpression) error {
+	err := c.Compile(node.Subject)
+	if err != nil {
+		return err
+	}
+
+	subject := c.symbolTable.defineHidden()
+	c.storeSymbol(subject)
+
+	endJumpPositions := []int{}
+	for _, arm := range node.Arms {
+		failJumpPositions := []int{}
+		// the names the arm binds, with what they meant outside of it
+		bindings := map[string]*Symbol{}
+
+		c.loadSymbol(subject)
+		err := c.compilePattern(arm.Pattern, bindings, &failJumpPositions)
+		if err != nil {
+			return err
+		}
+
+		if arm.Guard != nil {
+			err := c.Compile(arm.Guard)
+			if err != nil {
+				return err
+			}
+
+			failJumpPositions = append(failJumpPositions, c.emit(code.OpJumpNotTruthy, 9999))
+		}
+
+		err = c.Compile(arm.Body)
+		if err != nil {
+			return err
+		}
+
+		endJumpPositions = append(endJumpPositions, c.emit(code.OpJump, 9999))
+		c.symbolTable.restoreShadowed(bindings)
+
+		nextArmPos := len(c.currentInstructions())
+		for _, pos := range failJumpPositions {
+			c.changeOperand(pos, nextArmPos)
+		}
+	}
+
+	c.loadSymbol(subject)
+	c.emit(code.OpMatchFail)
+
+	afterMatchPos := len(c.currentInstructions())
+	for _, pos := range endJumpPositions {
+		c.changeOperand(pos, afterMatchPos)
+	}
+
+	return nil
+}
+
+// tests the value on top of the stack against the pattern and consumes it,
+// every failed test adds a jump that has to be patched to wherever matching
+// continues. names get fresh slots that only the arm can see, so an arm that
+// fails halfway never touches a variable of the same name outside of it
+func (c *Compiler) compilePattern(pattern ast.Pattern, bindings map[string]*Symbol, failJumpPositions *[]int) error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		c.emit(code.OpPop)
+	case *ast.BindingPattern:
+		symbol := c.symbolTable.defineShadowing(pattern.Name.Value, bindings)
+		c.storeSymbol(symbol)
+	case *ast.LiteralPattern:
+		err := c.Compile(pattern.Value)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpEqual)
+		*failJumpPositions = append(*failJumpPositions, c.emit(code.OpJumpNotTruthy, 9999))
+	case *ast.ArrayPattern:
+		array := c.symbolTable.defineHidden()
+		c.storeSymbol(array)
+
+		hasRest := 0
+		if pattern.Rest != nil {
+			hasRest = 1
+		}
+
+		c.loadSymbol(array)
+		c.emit(code.OpMatchArray, len(pattern.Elements), hasRest)
+		*failJumpPositions = append(*failJumpPositions, c.emit(code.OpJumpNotTruthy, 9999))
+
+		for i, element := range pattern.Elements {
+			c.loadSymbol(array)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(i)}))
+			c.emit(code.OpIndex)
+
+			err := c.compilePattern(element, bindings, failJumpPositions)
+			if err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil {
+			c.loadSymbol(array)
+			c.emit(code.OpArrayRest, len(pattern.Elements))
+
+			err := c.compilePattern(pattern.Rest, bindings, failJumpPositions)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.HashPattern:
+		hash := c.symbolTable.defineHidden()
+		c.storeSymbol(hash)
+
+		c.loadSymbol(hash)
+		c.emit(code.OpMatchHash)
+		*failJumpPositions = append(*failJumpPositions, c.emit(code.OpJumpNotTruthy, 9999))
+
+		for i, key := range pattern.Keys {
+			c.loadSymbol(hash)
+			err := c.Compile(key)
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpMatchKey)
+			*failJumpPositions = append(*failJumpPositions, c.emit(code.OpJumpNotTruthy, 9999))
+
+			c.loadSymbol(hash)
+			err = c.Compile(key)
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpIndex)
+
+			err = c.compilePattern(pattern.Values[i], bindings, failJumpPositions)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return c.errorAt(pattern, "unknown pattern %s", pattern.String())
+	}
+
+	return nil
+}
+
+func hasSpread(elements []ast.Expression) bool {
+	for _, element := range elements {
+		if _, ok := element.(*ast.SpreadExpression); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// leaves a single array on the stack, runs of plain elements are collected
+// with OpArray and every spread value is appended to what came before it
+func (c *Compiler) compileSpreadList(elements []ast.Expression) error {
+	started := false
+	pending := 0
+	flush := func() {
+		c.emit(code.OpArray, pending)
+		if started {
+			c.emit(code.OpArrayExtend)
+		}
+		started = true
+		pending = 0
+	}
+
+	for _, element := range elements {
+		spread, ok := element.(*ast.SpreadExpression)
+		if !ok {
+			err := c.Compile(element)
+			if err != nil {
+				return err
+			}
+			pending++
+			continue
+		}
+
+		if !started || pending > 0 {
+			flush()
+		}
+
+		err := c.Compile(spread.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpArrayExtend)
+	}
+
+	if pending > 0 {
+		flush()
+	}
+
+	return nil
+}
+
+// compiles a block whose value is left on the stack, the value of the last
+// expression statement or null when the block doesn't end with one
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// compiles the function into a closure and returns the symbols it captured
+func (c *Compiler) compileFunction(node *ast.FunctionExpression) ([]Symbol, error) {
+	c.enterScope()
+
+	if node.Name != "" {
+		c.symbolTable.DefineFunctionName(node.Name)
+	}
+
+	params := make([]Symbol, len(node.Parameters))
+	for i, param := range node.Parameters {
+		params[i] = c.symbolTable.Define(param.Value)
+	}
+
+	if node.Rest != nil {
+		c.symbolTable.Define(node.Rest.Value)
+	}
+
+	// a parameter left out of the call arrives as null and gets its default
+	numDefaults := 0
+	for i, defaultValue := range node.Defaults {
+		if defaultValue == nil {
+			continue
+		}
+		numDefaults++
+
+		c.loadSymbol(params[i])
+		c.emit(code.OpNull)
+		c.emit(code.OpEqual)
+		skipDefaultPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		err := c.Compile(defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		c.storeSymbol(params[i])
+
+		c.changeOperand(skipDefaultPos, len(c.currentInstructions()))
+	}
+
+	err := c.Compile(node.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	instructions := c.leaveScope()
+
+	// push the captured values in the order the closure stores them
+	for _, s := range freeSymbols {
+		c.loadSymbol(s)
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:       instructions,
+		NumberOfLocals:     numLocals,
+		NumberOfParameters: len(node.Parameters),
+		NumberOfDefaults:   numDefaults,
+		Variadic:           node.Rest != nil,
+	}
+	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+
+	return freeSymbols, nil
+}
+
 func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
@@ -383,6 +1165,63 @@ func (c *Compiler) loadSymbol(s Symbol) {
 		c.emit(code.OpGetLocal, s.Position)
 	case BuiltinScope:
 		c.emit(code.OpGetBuiltin, s.Position)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Position)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	if s.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, s.Position)
+	} else {
+		c.emit(code.OpSetLocal, s.Position)
+	}
+}
+
+func (c *Compiler) enterLoop(loop *LoopScope) {
+	scope := &c.compilerScopes[c.scopeIndex]
+	scope.loops = append(scope.loops, loop)
+}
+
+func (c *Compiler) leaveLoop() *LoopScope {
+	scope := &c.compilerScopes[c.scopeIndex]
+	loop := scope.loops[len(scope.loops)-1]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+	return loop
+}
+
+func (c *Compiler) currentLoop(statement ast.Statement) (*LoopScope, error) {
+	loops := c.compilerScopes[c.scopeIndex].loops
+	for i := len(loops) - 1; i >= 0; i-- {
+		if loops[i] == nil {
+			continue
+		}
+
+		if i != len(loops)-1 {
+			return nil, c.errorAt(statement, "%s cannot be used inside an expression", statement.TokenLiteral())
+		}
+
+		return loops[i], nil
+	}
+
+	return nil, c.errorAt(statement, "%s outside of a loop", statement.TokenLiteral())
+}
+
+// errors point at the node's span and quote the source when the compiler has it
+func (c *Compiler) errorAt(node ast.ASTNode, format string, a ...any) error {
+	span := node.GetSpan()
+	return &token.SourceError{Source: c.source, Start: span.Start, End: span.End, Message: fmt.Sprintf(format, a...)}
+}
+
+func (c *Compiler) patchLoopJumps(loop *LoopScope, continuePos, breakPos int) {
+	for _, pos := range loop.continuePositions {
+		c.changeOperand(pos, continuePos)
+	}
+
+	for _, pos := range loop.breakPositions {
+		c.changeOperand(pos, breakPos)
 	}
 }
 
@@ -406,3 +1245,30 @@ func (c *Compiler) leaveScope() code.Instructions {
 	c.symbolTable = c.symbolTable.Outer
 	return curr
 }
+
+// every part is turned into a string and the parts are added together left to right,
+// empty text around the expressions is left out
+func (c *Compiler) compileInterpolatedString(node *ast.InterpolatedString) error {
+	compiled := 0
+	for _, part := range node.Parts {
+		if str, ok := part.(*ast.StringLiteral); ok && str.Value == "" {
+			continue
+		}
+
+		err := c.Compile(part)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := part.(*ast.StringLiteral); !ok {
+			c.emit(code.OpToString)
+		}
+
+		if compiled > 0 {
+			c.emit(code.OpAdd)
+		}
+		compiled++
+	}
+
+	return nil
+}
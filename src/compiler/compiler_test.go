@@ -1,7 +1,9 @@
 package compiler
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/singlaanish56/Compiler-in-go/ast"
@@ -9,6 +11,7 @@ import (
 	"github.com/singlaanish56/Compiler-in-go/lexer"
 	"github.com/singlaanish56/Compiler-in-go/object"
 	"github.com/singlaanish56/Compiler-in-go/parser"
+	"github.com/singlaanish56/Compiler-in-go/token"
 )
 
 type testCompilerStructs struct {
@@ -25,6 +28,15 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"1*2", []any{1, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpMul), code.Make(code.OpPop)}},
 		{"2/1", []any{2, 1}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpDiv), code.Make(code.OpPop)}},
 		{"-1", []any{1}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpMinus), code.Make(code.OpPop)}},
+		{"5%2", []any{5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpMod), code.Make(code.OpPop)}},
+		{"5**2", []any{5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpPow), code.Make(code.OpPop)}},
+		{"5&2", []any{5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpBitAnd), code.Make(code.OpPop)}},
+		{"5|2", []any{5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpBitOr), code.Make(code.OpPop)}},
+		{"5^2", []any{5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpBitXor), code.Make(code.OpPop)}},
+		{"5<<2", []any{5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpShiftLeft), code.Make(code.OpPop)}},
+		{"5>>2", []any{5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpShiftRight), code.Make(code.OpPop)}},
+		{"~1", []any{1}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpBitNot), code.Make(code.OpPop)}},
+		{"1.5+2", []any{1.5, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpAdd), code.Make(code.OpPop)}},
 	}
 
 	runCompilerTests(t, tests)
@@ -36,6 +48,8 @@ func TestBooleanArithmetic(t *testing.T) {
 		{"false", []any{}, []code.Instructions{code.Make(code.OpFalse), code.Make(code.OpPop)}},
 		{"1>2", []any{1, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpGreaterThan), code.Make(code.OpPop)}},
 		{"1<2", []any{1, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpLessThan), code.Make(code.OpPop)}},
+		{"1>=2", []any{1, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpGreaterThanOrEqual), code.Make(code.OpPop)}},
+		{"1<=2", []any{1, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpLessThanOrEqual), code.Make(code.OpPop)}},
 		{"1==2", []any{1, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpEqual), code.Make(code.OpPop)}},
 		{"1!=2", []any{1, 2}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpConstant, 1), code.Make(code.OpNotEqual), code.Make(code.OpPop)}},
 		{"true==false", []any{}, []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpFalse), code.Make(code.OpEqual), code.Make(code.OpPop)}},
@@ -50,6 +64,266 @@ func TestConditionals(t *testing.T) {
 	tests := []testCompilerStructs{
 		{`if (true){10}; 3333;`, []any{10, 3333}, []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpJumpNotTruthy, 10), code.Make(code.OpConstant, 0), code.Make(code.OpJump, 11), code.Make(code.OpNull), code.Make(code.OpPop), code.Make(code.OpConstant, 1), code.Make(code.OpPop)}},
 		{`if (true){10}else{20}; 3333;`, []any{10, 20, 3333}, []code.Instructions{code.Make(code.OpTrue), code.Make(code.OpJumpNotTruthy, 10), code.Make(code.OpConstant, 0), code.Make(code.OpJump, 13), code.Make(code.OpConstant, 1), code.Make(code.OpPop), code.Make(code.OpConstant, 2), code.Make(code.OpPop)}},
+		{
+			`if (true){10}else if (false){20}else{30}; 3333;`,
+			[]any{10, 20, 30, 3333},
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 23),
+				code.Make(code.OpFalse),
+				code.Make(code.OpJumpNotTruthy, 20),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpJump, 23),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`if (true){10}else if (false){20}; 3333;`,
+			[]any{10, 20, 3333},
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 21),
+				code.Make(code.OpFalse),
+				code.Make(code.OpJumpNotTruthy, 20),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpJump, 21),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`match (1) { 1 => 10, _ => 20 }`,
+			[]any{1, 1, 10, 20},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpEqual),
+				code.Make(code.OpJumpNotTruthy, 22),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpJump, 36),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpJump, 36),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpMatchFail),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`match ([]) { [x, ...r] => x }`,
+			[]any{0},
+			[]code.Instructions{
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpMatchArray, 1, 1),
+				code.Make(code.OpJumpNotTruthy, 47),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpIndex),
+				code.Make(code.OpSetGlobal, 2),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpArrayRest, 1),
+				code.Make(code.OpSetGlobal, 3),
+				code.Make(code.OpGetGlobal, 2),
+				code.Make(code.OpJump, 51),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpMatchFail),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`while(true){ 10; }; 3333;`,
+			[]any{10, 3333},
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 11),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpJump, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`while(true){ break; continue; }`,
+			[]any{},
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 13),
+				code.Make(code.OpJump, 13),
+				code.Make(code.OpJump, 0),
+				code.Make(code.OpJump, 0),
+			},
+		},
+		{
+			`for(;true;1){ continue; }`,
+			[]any{1},
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 14),
+				code.Make(code.OpJump, 7),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpJump, 0),
+			},
+		},
+		{
+			`for(let i = 1;;){ break; }`,
+			[]any{1},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpJump, 12),
+				code.Make(code.OpJump, 6),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoopControlErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`break;`, "break outside of a loop"},
+		{`if(true){ continue; }`, "continue outside of a loop"},
+		{`while(true){ fn(){ break; } }`, "break outside of a loop"},
+		{`while(true){ let a = 1 + if(true){ break; }; }`, "break cannot be used inside an expression"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		err := compiler.Compile(program)
+		if err == nil {
+			t.Fatalf("expected compiler error for %q but got none", tt.input)
+		}
+
+		if message := errorMessage(err); message != tt.expected {
+			t.Errorf("wrong compiler error, expected=%q, got=%q", tt.expected, message)
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`true && false; 1;`,
+			[]any{1},
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthyOrPop, 5),
+				code.Make(code.OpFalse),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`1 || 2 && 3`,
+			[]any{1, 2, 3},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJumpTruthyOrPop, 15),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpJumpNotTruthyOrPop, 15),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`let [a, b] = [1, 2];`,
+			[]any{1, 2, 0, 1},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpIndex),
+				code.Make(code.OpSetGlobal, 2),
+			},
+		},
+		{
+			`let [a = 5] = [];`,
+			[]any{0, 5},
+			[]code.Instructions{
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpIndex),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpNull),
+				code.Make(code.OpEqual),
+				code.Make(code.OpJumpNotTruthy, 30),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+		{
+			`let a = 1; let b = 2; a, b = b, a;`,
+			[]any{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpSetGlobal, 2),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 3),
+				code.Make(code.OpGetGlobal, 2),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 3),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
 	}
 
 	runCompilerTests(t, tests)
@@ -64,6 +338,237 @@ func TestGlobalVariables(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestAssignments(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`let one = 1; one = 2;`,
+			[]any{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`let one = 1; one += 2;`,
+			[]any{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`fn(a){ a /= 2; }`,
+			[]any{
+				2,
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpDiv),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestIndexAssignments(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`let a = [1]; a[0] = 2;`,
+			[]any{1, 0, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`let h = {}; h[1] -= 2;`,
+			[]any{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpHash, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpIndexKeep),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSub),
+				code.Make(code.OpSetIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestParallelAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = 1; a, b = 1, 2;`, "cannot assign to undefined variable b"},
+		{`let a = 1; a, len = 1, 2;`, "cannot assign to builtin len"},
+		{`fn() { let a = 1; fn() { a, a = 1, 2; } }`, "cannot assign to a, it belongs to an enclosing function"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		err := compiler.Compile(program)
+		if err == nil {
+			t.Fatalf("expected compiler error for %q but got none", tt.input)
+		}
+
+		if message := errorMessage(err); message != tt.expected {
+			t.Errorf("wrong compiler error, expected=%q, got=%q", tt.expected, message)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir := filepath.Join("..", "testdata", "modules")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math.dm" as m; m.square(2)`, ""},
+		{`import "lib/math.dm" as m; fn() { m.square }`, ""},
+		{`import "lib/math.dm" as m; m.calls`, "module m has no export calls"},
+		{`import "lib/math.dm" as m; m.cube`, "module m has no export cube"},
+		{`import "lib/math.dm" as m; m`, "module m can only be used to reach its exports, like m.name"},
+		{`import "lib/math.dm" as m; m = 1`, "cannot assign to module m"},
+		{`let x = 1; x.y`, "x is not a module"},
+		{`[1].y`, "[1] is not a module"},
+		{`import "nope.dm" as n;`, fmt.Sprintf("cannot find module \"nope.dm\", looked for %s", filepath.Join(dir, "nope.dm"))},
+		{`import "a.dm" as a;`, "import cycle a.dm -> b.dm -> a.dm"},
+		{`import "bad.dm" as bad;`, "undefined variable missing"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		compiler.UseModules(NewModules(dir))
+		err := compiler.Compile(program)
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("unexpected compiler error for %q: %s", tt.input, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Fatalf("expected compiler error for %q but got none", tt.input)
+		}
+
+		if message := errorMessage(err); message != tt.expected {
+			t.Errorf("wrong compiler error, expected=%q, got=%q", tt.expected, message)
+		}
+	}
+}
+
+func TestMatchBindingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (1) { x => x }; x`, "undefined variable x"},
+		{`match ([1, 2]) { [a, b] if a > 5 => 0, _ => b }`, "undefined variable b"},
+		{`match (1) { x => 1, _ => x }`, "undefined variable x"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		err := compiler.Compile(program)
+		if err == nil {
+			t.Fatalf("expected compiler error for %q but got none", tt.input)
+		}
+
+		if message := errorMessage(err); message != tt.expected {
+			t.Errorf("wrong compiler error, expected=%q, got=%q", tt.expected, message)
+		}
+	}
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`x = 1;`, "cannot assign to undefined variable x"},
+		{`len = 1;`, "cannot assign to builtin len"},
+		{`fn(){ y -= 1; }`, "cannot assign to undefined variable y"},
+		{`fn(a){ fn(){ a = 1; } }`, "cannot assign to a, it belongs to an enclosing function"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		err := compiler.Compile(program)
+		if err == nil {
+			t.Fatalf("expected compiler error for %q but got none", tt.input)
+		}
+
+		if message := errorMessage(err); message != tt.expected {
+			t.Errorf("wrong compiler error, expected=%q, got=%q", tt.expected, message)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1;\nlet b = a + c;", "input:2:13: undefined variable c\nlet b = a + c;\n            ^"},
+		{"while(true){\n\tif(x){ 1 }\n}", "input:2:5: undefined variable x\n\tif(x){ 1 }\n\t   ^"},
+		{"let f = fn(){\n  break;\n};", "input:2:3: break outside of a loop\n  break;\n  ^^^^^^"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		err := compiler.Compile(program)
+		if err == nil {
+			t.Fatalf("expected compiler error for %q but got none", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error, expected=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
 func TestStringExpressions(t *testing.T) {
 	tests := []testCompilerStructs{
 		{`"monkey"`, []any{"monkey"}, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpPop)}},
@@ -102,6 +607,66 @@ func TestIndexExpressions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			"[1, 2][1:2]",
+			[]any{1, 2, 1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`"abc"[:1]`,
+			[]any{"abc", 1},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`"a ${1} b"`,
+			[]any{"a ", 1, " b"},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpToString),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`"${1}"`,
+			[]any{1},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpToString),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestFunctions(t *testing.T) {
 	tests := []testCompilerStructs{
 		{
@@ -117,7 +682,7 @@ func TestFunctions(t *testing.T) {
 				},
 			},
 			[]code.Instructions{
-				code.Make(code.OpConstant, 2),
+				code.Make(code.OpClosure, 2, 0),
 				code.Make(code.OpPop),
 			},
 		},
@@ -134,7 +699,7 @@ func TestFunctions(t *testing.T) {
 				},
 			},
 			[]code.Instructions{
-				code.Make(code.OpConstant, 2),
+				code.Make(code.OpClosure, 2, 0),
 				code.Make(code.OpPop),
 			},
 		},
@@ -151,7 +716,7 @@ func TestFunctions(t *testing.T) {
 				},
 			},
 			[]code.Instructions{
-				code.Make(code.OpConstant, 2),
+				code.Make(code.OpClosure, 2, 0),
 				code.Make(code.OpPop),
 			},
 		},
@@ -162,8 +727,73 @@ func TestFunctions(t *testing.T) {
 					code.Make(code.OpReturn),
 				},
 			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestDefaultParameters(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`fn(a, b = 2) { a + b }`,
+			[]any{
+				2,
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpNull),
+					code.Make(code.OpEqual),
+					code.Make(code.OpJumpNotTruthy, 12),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestSpread(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`let a = [1]; [0, ...a]`,
+			[]any{1, 0},
 			[]code.Instructions{
 				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpArrayExtend),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`let a = [1]; len(...a)`,
+			[]any{1},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetBuiltin, 0),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpArrayExtend),
+				code.Make(code.OpCallSpread),
 				code.Make(code.OpPop),
 			},
 		},
@@ -244,7 +874,7 @@ func TestFunctionCalls(t *testing.T) {
 				},
 			},
 			[]code.Instructions{
-				code.Make(code.OpConstant, 1),
+				code.Make(code.OpClosure, 1, 0),
 				code.Make(code.OpCall, 0),
 				code.Make(code.OpPop),
 			},
@@ -260,7 +890,7 @@ func TestFunctionCalls(t *testing.T) {
 				},
 			},
 			[]code.Instructions{
-				code.Make(code.OpConstant, 1),
+				code.Make(code.OpClosure, 1, 0),
 				code.Make(code.OpSetGlobal, 0),
 				code.Make(code.OpGetGlobal, 0),
 				code.Make(code.OpCall, 0),
@@ -277,7 +907,7 @@ func TestFunctionCalls(t *testing.T) {
 				24,
 			},
 			[]code.Instructions{
-				code.Make(code.OpConstant, 0),
+				code.Make(code.OpClosure, 0, 0),
 				code.Make(code.OpSetGlobal, 0),
 				code.Make(code.OpGetGlobal, 0),
 				code.Make(code.OpConstant, 1),
@@ -301,7 +931,7 @@ func TestFunctionCalls(t *testing.T) {
 				36,
 			},
 			[]code.Instructions{
-				code.Make(code.OpConstant, 0),
+				code.Make(code.OpClosure, 0, 0),
 				code.Make(code.OpSetGlobal, 0),
 				code.Make(code.OpGetGlobal, 0),
 				code.Make(code.OpConstant, 1),
@@ -320,15 +950,186 @@ func TestLetStatementScopes(t *testing.T) {
 	tests := []testCompilerStructs{
 		{`let num=55; fn(){num}`,
 			[]any{55, []code.Instructions{code.Make(code.OpGetGlobal, 0), code.Make(code.OpReturnValue)}},
-			[]code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpSetGlobal, 0), code.Make(code.OpConstant, 1), code.Make(code.OpPop)},
+			[]code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpSetGlobal, 0), code.Make(code.OpClosure, 1, 0), code.Make(code.OpPop)},
 		},
 		{`fn(){let num=55;  num}`,
 			[]any{55, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpSetLocal, 0), code.Make(code.OpGetLocal, 0), code.Make(code.OpReturnValue)}},
-			[]code.Instructions{code.Make(code.OpConstant, 1), code.Make(code.OpPop)},
+			[]code.Instructions{code.Make(code.OpClosure, 1, 0), code.Make(code.OpPop)},
 		},
 		{`fn(){let a=55;let b= 77;  a+b}`,
 			[]any{55, 77, []code.Instructions{code.Make(code.OpConstant, 0), code.Make(code.OpSetLocal, 0), code.Make(code.OpConstant, 1), code.Make(code.OpSetLocal, 1), code.Make(code.OpGetLocal, 0), code.Make(code.OpGetLocal, 1), code.Make(code.OpAdd), code.Make(code.OpReturnValue)}},
-			[]code.Instructions{code.Make(code.OpConstant, 2), code.Make(code.OpPop)},
+			[]code.Instructions{code.Make(code.OpClosure, 2, 0), code.Make(code.OpPop)},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`fn(a){ fn(b){ a+b } }`,
+			[]any{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`fn(a){ fn(b){ fn(c){ a+b+c } } }`,
+			[]any{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetFree, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 2),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 1, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`let global = 55;
+			fn(){
+				let a = 66;
+				fn(){
+					let b = 77;
+					fn(){
+						let c = 88;
+						global + a + b + c;
+					}
+				}
+			}`,
+			[]any{
+				55,
+				66,
+				77,
+				88,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpGetFree, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 4, 2),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 5, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpClosure, 6, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []testCompilerStructs{
+		{
+			`let countDown = fn(x){ countDown(x - 1); }; countDown(1);`,
+			[]any{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpCurrentClosure),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			`let wrapper = fn(){
+				let isEven = fn(){ isOdd(); };
+				let isOdd = fn(){ isEven(); };
+			};`,
+			[]any{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 1, 1),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpPatchFree, 0),
+					code.Make(code.OpReturn),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpSetGlobal, 0),
+			},
 		},
 	}
 
@@ -407,6 +1208,16 @@ func parse(input string) *ast.AstRootNode {
 	return p.ParserProgram()
 }
 
+// the message without the position and source snippet
+func errorMessage(err error) string {
+	var sourceErr *token.SourceError
+	if errors.As(err, &sourceErr) {
+		return sourceErr.Message
+	}
+
+	return err.Error()
+}
+
 func testInstructions(actual code.Instructions, expected []code.Instructions) error {
 
 	concatted := concatInstructions(expected)
@@ -436,6 +1247,11 @@ func testConstants(actual []object.Object, expected []any) error {
 			if err != nil {
 				return fmt.Errorf("constant at index %d, expected=%d, got=%s", i, constant, err)
 			}
+		case float64:
+			err := testFloatObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testFloatObject failed: %s", i, err)
+			}
 		case string:
 			err := testStringObject(constant, actual[i])
 			if err != nil {
@@ -481,6 +1297,19 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float, got=%T", actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value, expected=%g, got=%g", expected, result.Value)
+	}
+
+	return nil
+}
+
 func testStringObject(expected string, actual object.Object) error {
 	result, ok := actual.(*object.String)
 	if !ok {
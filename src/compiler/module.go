@@ -0,0 +1,206 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/singlaanish56/Compiler-in-go/ast"
+	"github.com/singlaanish56/Compiler-in-go/lexer"
+	"github.com/singlaanish56/Compiler-in-go/object"
+	"github.com/singlaanish56/Compiler-in-go/parser"
+)
+
+// a file compiled into the program by an import. its code runs once, where it
+// is first imported, and every later import shares its globals
+type module struct {
+	path    string
+	exports map[string]Symbol
+}
+
+// the files imported while compiling a program, a compiler reused across
+// several compilations like the repl's keeps the same modules
+type Modules struct {
+	// directories searched in order for an import that is not relative to the importing file
+	SearchPath []string
+
+	loaded []*module
+	byPath map[string]int
+	// the files being compiled, importing one of them again is a cycle
+	importing []string
+}
+
+func NewModules(searchPath ...string) *Modules {
+	return &Modules{SearchPath: searchPath, byPath: make(map[string]int)}
+}
+
+// how many modules are loaded, Restore forgets the ones loaded after that
+func (m *Modules) Save() int {
+	return len(m.loaded)
+}
+
+// a module whose importing line failed has not run, importing it again
+// has to compile it again
+func (m *Modules) Restore(loaded int) {
+	for path, index := range m.byPath {
+		if index >= loaded {
+			delete(m.byPath, path)
+		}
+	}
+
+	m.loaded = m.loaded[:loaded]
+	m.importing = m.importing[:0]
+}
+
+// ./ and ../ are relative to the importing file, an absolute path is used as
+// it is and anything else is looked up on the search path
+func (m *Modules) resolve(importer string, path string) (string, error) {
+	candidates := []string{}
+	switch {
+	case filepath.IsAbs(path):
+		candidates = append(candidates, path)
+	case strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../"):
+		candidates = append(candidates, filepath.Join(filepath.Dir(importer), path))
+	default:
+		searchPath := m.SearchPath
+		if len(searchPath) == 0 {
+			searchPath = []string{"."}
+		}
+		for _, dir := range searchPath {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err == nil && !info.IsDir() {
+			return filepath.Abs(candidate)
+		}
+	}
+
+	return "", fmt.Errorf("cannot find module %q, looked for %s", path, strings.Join(candidates, ", "))
+}
+
+// the chain of imports that leads back to path, a.dm -> b.dm -> a.dm
+func (m *Modules) cycle(path string) (string, bool) {
+	for i, importing := range m.importing {
+		if importing != path {
+			continue
+		}
+
+		names := []string{}
+		for _, p := range append(m.importing[i:], path) {
+			names = append(names, filepath.Base(p))
+		}
+		return strings.Join(names, " -> "), true
+	}
+
+	return "", false
+}
+
+func (c *Compiler) compileImport(node *ast.ImportStatement) error {
+	importer := ""
+	if c.source != nil {
+		importer = c.source.Name
+	}
+
+	path, err := c.modules.resolve(importer, node.Path.Value)
+	if err != nil {
+		return c.errorAt(node.Path, "%s", err)
+	}
+
+	// the file the program itself came from takes part in cycles as well
+	if len(c.modules.importing) == 0 && importer != "" {
+		if abs, err := filepath.Abs(importer); err == nil {
+			c.modules.importing = append(c.modules.importing, abs)
+			defer func() { c.modules.importing = c.modules.importing[:0] }()
+		}
+	}
+
+	if chain, ok := c.modules.cycle(path); ok {
+		return c.errorAt(node.Path, "import cycle %s", chain)
+	}
+
+	index, ok := c.modules.byPath[path]
+	if !ok {
+		index, err = c.compileModule(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.symbolTable.DefineModule(index, node.Alias.Value)
+	return nil
+}
+
+// the module's code is emitted in place, with a symbol table of its own whose
+// globals sit next to the program's in the same global store
+func (c *Compiler) compileModule(path string) (int, error) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	p := parser.New(lexer.NewWithFile(path, string(input)))
+	program := p.ParserProgram()
+	if len(p.Errors()) != 0 {
+		return 0, errors.Join(p.Errors()...)
+	}
+
+	symbolTable := NewModuleSymbolTable(c.symbolTable)
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	outerSymbolTable, outerSource := c.symbolTable, c.source
+	c.symbolTable, c.source = symbolTable, program.Source
+	c.modules.importing = append(c.modules.importing, path)
+
+	err = c.compileStatements(program.Statements)
+
+	c.modules.importing = c.modules.importing[:len(c.modules.importing)-1]
+	c.symbolTable, c.source = outerSymbolTable, outerSource
+	if err != nil {
+		return 0, err
+	}
+
+	exports := make(map[string]Symbol)
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok || !let.Exported {
+			continue
+		}
+
+		exports[let.Variable.Value], _ = symbolTable.Resolve(let.Variable.Value)
+	}
+
+	c.modules.loaded = append(c.modules.loaded, &module{path: path, exports: exports})
+	c.modules.byPath[path] = len(c.modules.loaded) - 1
+	return len(c.modules.loaded) - 1, nil
+}
+
+// m.name reads the global the module exported as name
+func (c *Compiler) compileMemberExpression(node *ast.MemberExpression) error {
+	name, ok := node.Left.(*ast.Variable)
+	if !ok {
+		return c.errorAt(node.Left, "%s is not a module", node.Left.String())
+	}
+
+	symbol, ok := c.symbolTable.Resolve(name.Value)
+	if !ok {
+		return c.errorAt(name, "undefined variable %s", name.Value)
+	}
+	if symbol.Scope != ModuleScope {
+		return c.errorAt(name, "%s is not a module", name.Value)
+	}
+
+	export, ok := c.modules.loaded[symbol.Position].exports[node.Property.Value]
+	if !ok {
+		return c.errorAt(node, "module %s has no export %s", name.Value, node.Property.Value)
+	}
+
+	c.loadSymbol(export)
+	return nil
+}
@@ -3,9 +3,12 @@ package compiler
 type SymbolScope string
 
 const (
-	GlobalScope  SymbolScope = "GLOBAL"
-	LocalScope   SymbolScope = "LOCAL"
-	BuiltinScope SymbolScope = "BUILTIN"
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+	ModuleScope   SymbolScope = "MODULE"
 )
 
 type Symbol struct {
@@ -19,34 +22,118 @@ type SymbolTable struct {
 
 	store          map[string]Symbol
 	numDefinitions int
+	// the next free global slot, shared by the top level tables of every
+	// file compiled into one program
+	nextGlobal *int
+
+	// the symbols of the enclosing scopes this function captures,
+	// in the order the closure expects them on the stack
+	FreeSymbols []Symbol
 }
 
 func NewSymbolTable() *SymbolTable {
 	return &SymbolTable{
 		store:          make(map[string]Symbol),
 		numDefinitions: 0,
+		nextGlobal:     new(int),
+		FreeSymbols:    []Symbol{},
 	}
 }
 
+// the top level of an imported file, its globals go after the ones
+// the program has already taken
+func NewModuleSymbolTable(program *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.nextGlobal = program.nextGlobal
+	return s
+}
+
 func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 	s := NewSymbolTable()
 	s.Outer = outer
 	return s
 }
 
+// the names and slots of a symbol table at one point, the repl puts them
+// back when a line fails so its definitions don't outlive it
+type SymbolTableState struct {
+	store          map[string]Symbol
+	numDefinitions int
+	nextGlobal     int
+}
+
+func (st *SymbolTable) Save() SymbolTableState {
+	store := make(map[string]Symbol, len(st.store))
+	for name, symbol := range st.store {
+		store[name] = symbol
+	}
+
+	return SymbolTableState{store: store, numDefinitions: st.numDefinitions, nextGlobal: *st.nextGlobal}
+}
+
+func (st *SymbolTable) Restore(state SymbolTableState) {
+	st.store = state.store
+	st.numDefinitions = state.numDefinitions
+	*st.nextGlobal = state.nextGlobal
+}
+
 func (st *SymbolTable) Define(name string) Symbol {
-	symbol := Symbol{name, GlobalScope, st.numDefinitions}
-	if st.Outer == nil {
-		symbol.Scope = GlobalScope
-	} else {
+	symbol := st.defineHidden()
+	symbol.Name = name
+
+	st.store[name] = symbol
+	return symbol
+}
+
+// a slot the compiler keeps a value in, it has no name so code can't refer to it
+func (st *SymbolTable) defineHidden() Symbol {
+	symbol := Symbol{Scope: GlobalScope, Position: st.numDefinitions}
+	if st.Outer != nil {
 		symbol.Scope = LocalScope
+	} else {
+		symbol.Position = *st.nextGlobal
+		*st.nextGlobal++
 	}
 
-	st.store[name] = symbol
 	st.numDefinitions++
 	return symbol
 }
 
+// binds the name an imported file is reached through, the position is the
+// index of the module in the program's modules
+func (st *SymbolTable) DefineModule(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Position: index, Scope: ModuleScope}
+	st.store[name] = symbol
+
+	return symbol
+}
+
+// binds a name to a fresh slot for a while, what the name meant before is
+// kept in shadowed the first time it is bound so restoreShadowed can put it back
+func (st *SymbolTable) defineShadowing(name string, shadowed map[string]*Symbol) Symbol {
+	if _, seen := shadowed[name]; !seen {
+		shadowed[name] = nil
+		if previous, ok := st.store[name]; ok {
+			shadowed[name] = &previous
+		}
+	}
+
+	symbol := st.defineHidden()
+	symbol.Name = name
+	st.store[name] = symbol
+	return symbol
+}
+
+func (st *SymbolTable) restoreShadowed(shadowed map[string]*Symbol) {
+	for name, previous := range shadowed {
+		if previous == nil {
+			delete(st.store, name)
+		} else {
+			st.store[name] = *previous
+		}
+	}
+}
+
 func (st *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 	symbol := Symbol{Name: name, Position: index, Scope: BuiltinScope}
 	st.store[name] = symbol
@@ -54,11 +141,38 @@ func (st *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 	return symbol
 }
 
+// binds the name of the function being compiled so its body can call itself
+func (st *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Position: 0, Scope: FunctionScope}
+	st.store[name] = symbol
+
+	return symbol
+}
+
+func (st *SymbolTable) defineFree(original Symbol) Symbol {
+	st.FreeSymbols = append(st.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Position: len(st.FreeSymbols) - 1, Scope: FreeScope}
+	st.store[original.Name] = symbol
+
+	return symbol
+}
+
 func (st *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := st.store[name]
 	if !ok && st.Outer != nil {
 		obj, ok = st.Outer.Resolve(name)
-		return obj, ok
+		if !ok {
+			return obj, ok
+		}
+
+		// globals, builtins and modules are reachable from every frame, anything
+		// else belongs to an enclosing function and has to be captured
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope || obj.Scope == ModuleScope {
+			return obj, ok
+		}
+
+		return st.defineFree(obj), true
 	}
 	return obj, ok
 }
@@ -124,3 +124,194 @@ func TestDefineResolveWithBuiltins(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+	firstLocal.Define("d")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+	secondLocal.Define("f")
+
+	tests := []struct {
+		table               *SymbolTable
+		expectedSymbols     []Symbol
+		expectedFreeSymbols []Symbol
+	}{
+		{
+			firstLocal,
+			[]Symbol{
+				Symbol{"a", GlobalScope, 0},
+				Symbol{"b", GlobalScope, 1},
+				Symbol{"c", LocalScope, 0},
+				Symbol{"d", LocalScope, 1},
+			},
+			[]Symbol{},
+		},
+		{
+			secondLocal,
+			[]Symbol{
+				Symbol{"a", GlobalScope, 0},
+				Symbol{"b", GlobalScope, 1},
+				Symbol{"c", FreeScope, 0},
+				Symbol{"d", FreeScope, 1},
+				Symbol{"e", LocalScope, 0},
+				Symbol{"f", LocalScope, 1},
+			},
+			[]Symbol{
+				Symbol{"c", LocalScope, 0},
+				Symbol{"d", LocalScope, 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, got := range tt.expectedSymbols {
+			result, ok := tt.table.Resolve(got.Name)
+			if !ok {
+				t.Errorf("name %s is not found in the symbol table", got.Name)
+				continue
+			}
+			if result != got {
+				t.Errorf("expected %s to resolve to %+v, got=%+v", got.Name, got, result)
+			}
+		}
+
+		if len(tt.table.FreeSymbols) != len(tt.expectedFreeSymbols) {
+			t.Errorf("wrong number of free symbols, expected=%d, got=%d", len(tt.expectedFreeSymbols), len(tt.table.FreeSymbols))
+			continue
+		}
+
+		for i, got := range tt.expectedFreeSymbols {
+			result := tt.table.FreeSymbols[i]
+			if result != got {
+				t.Errorf("wrong free symbol, expected=%+v, got=%+v", got, result)
+			}
+		}
+	}
+}
+
+func TestResolveUnresolvableFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+
+	for _, name := range []string{"b", "d"} {
+		_, ok := secondLocal.Resolve(name)
+		if ok {
+			t.Errorf("name %s resolved, but was expected not to", name)
+		}
+	}
+
+	if len(secondLocal.FreeSymbols) != 0 {
+		t.Errorf("unresolved names should not be captured, got=%+v", secondLocal.FreeSymbols)
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+
+	expected := Symbol{Name: "a", Scope: FunctionScope, Position: 0}
+
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s is not resolvable", expected.Name)
+	}
+
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestShadowingFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	global.Define("a")
+
+	expected := Symbol{Name: "a", Scope: GlobalScope, Position: 0}
+
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s is not resolvable", expected.Name)
+	}
+
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestModuleSymbolTableSharesGlobals(t *testing.T) {
+	program := NewSymbolTable()
+	program.Define("a")
+
+	module := NewModuleSymbolTable(program)
+	module.Define("b")
+	program.Define("c")
+	program.DefineModule(0, "m")
+
+	expected := map[*SymbolTable][]Symbol{
+		program: {
+			{Name: "a", Scope: GlobalScope, Position: 0},
+			{Name: "c", Scope: GlobalScope, Position: 2},
+			{Name: "m", Scope: ModuleScope, Position: 0},
+		},
+		module: {
+			{Name: "b", Scope: GlobalScope, Position: 1},
+		},
+	}
+
+	for table, symbols := range expected {
+		for _, sym := range symbols {
+			result, ok := table.Resolve(sym.Name)
+			if !ok {
+				t.Fatalf("name %s not resolvable", sym.Name)
+			}
+
+			if result != sym {
+				t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+			}
+		}
+	}
+
+	if _, ok := module.Resolve("a"); ok {
+		t.Errorf("a global of the program should not be visible in the module")
+	}
+
+	local := NewEnclosedSymbolTable(program)
+	result, ok := local.Resolve("m")
+	if !ok || result.Scope != ModuleScope || len(local.FreeSymbols) != 0 {
+		t.Errorf("expected m to resolve to a module without being captured, got=%+v", result)
+	}
+}
+
+func TestRestoreForgetsLaterDefinitions(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	state := global.Save()
+	global.Define("b")
+	global.DefineModule(0, "m")
+	global.Restore(state)
+
+	for _, name := range []string{"b", "m"} {
+		if _, ok := global.Resolve(name); ok {
+			t.Errorf("expected %s to be forgotten", name)
+		}
+	}
+
+	expected := Symbol{Name: "c", Scope: GlobalScope, Position: 1}
+	if result := global.Define("c"); result != expected {
+		t.Errorf("expected c to reuse the slot of b, want=%+v, got=%+v", expected, result)
+	}
+}
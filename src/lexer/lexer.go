@@ -1,6 +1,12 @@
 package lexer
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/singlaanish56/Compiler-in-go/token"
 )
@@ -11,20 +17,157 @@ type Lexer struct {
 	char rune
 	currentPosition int
 	nextReadPosition int
+
+	source *token.Source
+	// offsets where each line seen so far begins
+	lineStarts []int
+
+	// the last token handed out, a newline after some of them ends the statement
+	lastType token.TokenType
+	// one entry per open ( [ or {, true when it holds statements, which is only a block
+	statementContexts []bool
+	// the nesting depth inside each open ${ of a string, its } carries on the string
+	interpolationDepths []int
 }
 
 func New(input string) *Lexer{
-	lexer := &Lexer{input: []rune(input), nextReadPosition: 0}
+	return NewWithFile("input", input)
+}
+
+// the file name is only used when reporting positions
+func NewWithFile(name string, input string) *Lexer{
+	lexer := &Lexer{input: []rune(input), nextReadPosition: 0, source: token.NewSource(name, input), lineStarts: []int{0}}
 	lexer.nextChar()
 	return lexer
 }
 
+func (l *Lexer) Source() *token.Source{
+	return l.source
+}
+
 func (l *Lexer) NextToken() token.Token{
- 
-	for isEscapeSequence(l.char){
-		l.nextChar()
+	comments, tk := l.skipTrivia()
+	if tk == nil{
+		next := l.retrieveTheToken()
+		tk = &next
+	}
+
+	tk.Comments = comments
+	l.trackNesting(tk.Type)
+	return l.withPosition(*tk)
+}
+
+func (l *Lexer) trackNesting(tokenType token.TokenType){
+	switch tokenType{
+	case token.OPENROUND, token.OPENBRACKET:
+		l.statementContexts = append(l.statementContexts, false)
+	case token.OPENBRACE:
+		// blocks only ever follow the ) of an if, while, for or fn header, or an else
+		block := l.lastType == token.CLOSEROUND || l.lastType == token.ELSE
+		l.statementContexts = append(l.statementContexts, block)
+	case token.CLOSEROUND, token.CLOSEBRACKET, token.CLOSEBRACE:
+		if len(l.statementContexts) > 0{
+			l.statementContexts = l.statementContexts[:len(l.statementContexts)-1]
+		}
+	}
+
+	l.lastType = tokenType
+}
+
+// go style semicolon insertion, a newline ends the statement when the line ends in an operand,
+// a closing bracket, break, continue or return, unless the next line obviously carries on
+func (l *Lexer) newlineEndsStatement() bool{
+	if len(l.statementContexts) > 0 && !l.statementContexts[len(l.statementContexts)-1]{
+		return false
 	}
 
+	switch l.lastType{
+	case token.VARIABLE, token.NUMBER, token.FLOAT, token.STRING, token.STRINGTAIL, token.TRUE, token.FALSE, token.NULL,
+		token.BREAK, token.CONTINUE, token.RETURN, token.CLOSEROUND, token.CLOSEBRACKET, token.CLOSEBRACE:
+	default:
+		return false
+	}
+
+	return !l.nextLineContinues()
+}
+
+// looks past whitespace and comments for an else, the { of a block, or a binary operator
+func (l *Lexer) nextLineContinues() bool{
+	i := l.currentPosition
+	for i < len(l.input){
+		switch{
+		case isEscapeSequence(l.input[i]):
+			i++
+		case l.runeAt(i) == '/' && l.runeAt(i+1) == '/':
+			for i < len(l.input) && l.input[i] != '\n'{
+				i++
+			}
+		case l.runeAt(i) == '/' && l.runeAt(i+1) == '*':
+			depth := 0
+			for i < len(l.input){
+				if l.runeAt(i) == '/' && l.runeAt(i+1) == '*'{
+					depth++
+					i++
+				}else if l.runeAt(i) == '*' && l.runeAt(i+1) == '/'{
+					depth--
+					i++
+					if depth == 0{
+						i++
+						break
+					}
+				}
+				i++
+			}
+		default:
+			return l.continuesAt(i)
+		}
+	}
+
+	return false
+}
+
+func (l *Lexer) continuesAt(i int) bool{
+	switch l.runeAt(i){
+	case '{':
+		return l.lastType == token.CLOSEROUND
+	case '!':
+		return l.runeAt(i+1) == '='
+	case '*', '/', '%', '&', '|', '^', '<', '>', '=', ',', ':':
+		return true
+	case ';':
+		// an explicit semicolon at the start of the next line ends the statement itself
+		return true
+	case 'e':
+		return string(l.input[i:min(i+4, len(l.input))]) == "else" && !isIdentifierChar(l.runeAt(i+4))
+	}
+
+	return false
+}
+
+func (l *Lexer) runeAt(i int) rune{
+	if i >= len(l.input){
+		return 0
+	}
+
+	return l.input[i]
+}
+
+func (l *Lexer) withPosition(tk token.Token) token.Token{
+	start := l.position(tk.StartPosition)
+	end := l.position(tk.EndPosition)
+	tk.Line, tk.Column = start.Line, start.Column
+	tk.EndLine, tk.EndColumn = end.Line, end.Column
+	return tk
+}
+
+// the line and column of an offset the lexer has already read past
+func (l *Lexer) position(offset int) token.Position{
+	line := sort.Search(len(l.lineStarts), func(i int) bool{ return l.lineStarts[i] > offset })
+	return token.Position{Offset: offset, Line: line, Column: offset - l.lineStarts[line-1] + 1}
+}
+
+func (l *Lexer) retrieveTheToken() token.Token{
+
 	// is it a number
 	if l.char>= '0' && l.char<='9' {
 		return l.retrieveTheNumber()
@@ -32,11 +175,22 @@ func (l *Lexer) NextToken() token.Token{
 
 	// is it string
 	if l.char == '"'{
-		return l.retrieveTheString()
+		return l.retrieveTheString(token.STRING, token.STRINGHEAD)
+	}
+
+	// the } that closes a ${ inside a string
+	if l.char == '}' && l.closesInterpolation(){
+		l.interpolationDepths = l.interpolationDepths[:len(l.interpolationDepths)-1]
+		l.statementContexts = l.statementContexts[:len(l.statementContexts)-1]
+		return l.retrieveTheString(token.STRINGTAIL, token.STRINGMIDDLE)
 	}
 
-	// any variables
-	if((l.char>='a' && l.char<='z') || (l.char>='A' && l.char<='Z')){
+	if l.char == '`'{
+		return l.retrieveTheRawString()
+	}
+
+	// any variables, a lone _ stays its own token
+	if isLetter(l.char) && (l.char != '_' || isIdentifierChar(l.peekChar())){
 		return l.retrieveTheVariable()
 	}
 
@@ -46,7 +200,70 @@ func (l *Lexer) NextToken() token.Token{
 	return  tk
 }
 
+// skips whitespace and comments, returning the comment text, plus a semicolon when a newline
+// ends the statement or an error token for an unclosed block comment
+func (l *Lexer) skipTrivia() ([]string, *token.Token){
+	var comments []string
+	for {
+		switch{
+		case l.char == '\n' && l.newlineEndsStatement():
+			return comments, &token.Token{Type: token.SEMICOLON, Identifier: "\n", StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+		case isEscapeSequence(l.char):
+			l.nextChar()
+		case l.char == '/' && l.peekChar() == '/':
+			comments = append(comments, l.retrieveTheLineComment())
+		case l.char == '/' && l.peekChar() == '*':
+			start := l.currentPosition
+			comment, ok := l.retrieveTheBlockComment()
+			if !ok{
+				return comments, &token.Token{Type: token.ERROR, Identifier: "unterminated block comment", StartPosition: start, EndPosition: l.currentPosition}
+			}
+			comments = append(comments, comment)
+			if strings.Contains(comment, "\n") && l.newlineEndsStatement(){
+				return comments, &token.Token{Type: token.SEMICOLON, Identifier: "\n", StartPosition: start, EndPosition: start+1}
+			}
+		default:
+			return comments, nil
+		}
+	}
+}
+
+func (l *Lexer) retrieveTheLineComment() string{
+	start := l.currentPosition
+	for !l.atEnd() && l.char != '\n'{
+		l.nextChar()
+	}
+
+	return string(l.input[start:l.currentPosition])
+}
+
+// block comments nest, so /* a /* b */ c */ is a single comment
+func (l *Lexer) retrieveTheBlockComment() (string, bool){
+	start := l.currentPosition
+	depth := 0
+	for !l.atEnd(){
+		if l.char == '/' && l.peekChar() == '*'{
+			depth++
+			l.nextChar()
+		}else if l.char == '*' && l.peekChar() == '/'{
+			depth--
+			l.nextChar()
+			if depth == 0{
+				l.nextChar()
+				return string(l.input[start:l.currentPosition]), true
+			}
+		}
+		l.nextChar()
+	}
+
+	return "", false
+}
+
 func (l *Lexer) nextChar(){
+	if l.char == '\n'{
+		l.lineStarts = append(l.lineStarts, l.nextReadPosition)
+	}
+
 	if l.nextReadPosition>= len(l.input){
 		l.char = 0
 	}else{
@@ -58,50 +275,215 @@ func (l *Lexer) nextChar(){
 }
 
 func (l *Lexer) peekChar() rune{
-	if l.currentPosition >= len(l.input){
+	if l.nextReadPosition >= len(l.input){
 		return 0
 	}
 
 	return l.input[l.nextReadPosition]
 }
 
+// the character offset places after the current one, 0 past the end
+func (l *Lexer) peekCharAt(offset int) rune{
+	position := l.currentPosition + offset
+	if position >= len(l.input){
+		return 0
+	}
+
+	return l.input[position]
+}
+
 func (l *Lexer) retrieveTheNumber() token.Token{
 	start := l.currentPosition
+	tokenType := token.TokenType(token.NUMBER)
 
-	for l.char >= '0' && l.char <= '9'{
+	// 0x, 0o and 0b prefixes, the digits are validated by strconv in the parser
+	if l.char == '0' && isBasePrefix(l.peekChar()){
 		l.nextChar()
+		l.nextChar()
+		for isIdentifierChar(l.char){
+			l.nextChar()
+		}
+
+		return token.Token{Type: tokenType, Identifier : string(l.input[start:l.currentPosition]), StartPosition: start, EndPosition: l.currentPosition}
+	}
+
+	l.skipDigits()
+
+	// a fraction needs a digit after the dot
+	if l.char == '.' && isDigit(l.peekChar()){
+		tokenType = token.FLOAT
+		l.nextChar()
+		l.skipDigits()
+	}
+
+	// an exponent needs a digit after the e and its optional sign
+	if l.char == 'e' || l.char == 'E'{
+		offset := 1
+		if l.peekChar() == '+' || l.peekChar() == '-'{
+			offset = 2
+		}
+
+		if isDigit(l.peekCharAt(offset)){
+			tokenType = token.FLOAT
+			for i := 0; i < offset; i++{
+				l.nextChar()
+			}
+			l.skipDigits()
+		}
 	}
 
-	return token.Token{Type: token.NUMBER, Identifier : string(l.input[start:l.currentPosition]), StartPosition: start, EndPosition: l.currentPosition}
+	return token.Token{Type: tokenType, Identifier : string(l.input[start:l.currentPosition]), StartPosition: start, EndPosition: l.currentPosition}
 }
 
-func (l *Lexer) retrieveTheString() token.Token{
+// digits may be separated by underscores, like 1_000_000
+func (l *Lexer) skipDigits(){
+	for isDigit(l.char) || l.char == '_'{
+		l.nextChar()
+	}
+}
+
+// reads up to the closing quote, or up to a ${ which ends this part of an interpolated string.
+// the token is closed when the quote is reached and open when the string carries on after an expression
+func (l *Lexer) retrieveTheString(closed token.TokenType, open token.TokenType) token.Token{
 	start := l.currentPosition+1
 	var strBuilder []rune
+	// the first bad escape, reported once the closing quote is found
+	var escapeErr error
+	tokenType := closed
 	for {
 		l.nextChar()
 
-		if l.char=='"' || l.char==0{
+		if l.atEnd(){
+			return token.Token{Type: token.ERROR, Identifier: "unterminated string", StartPosition: start-1, EndPosition: l.currentPosition}
+		}
+
+		if l.char=='"'{
+			break
+		}
+
+		if l.char=='$' && l.peekChar()=='{'{
+			l.nextChar()
+			tokenType = open
 			break
 		}
 
-		if l.char=='\n' || l.char=='\t' || l.char=='\r'{
+		if l.char=='\\'{
+			l.nextChar()
+			if l.atEnd(){
+				continue
+			}
+
+			ch, err := l.retrieveTheEscape()
+			if err != nil && escapeErr == nil{
+				escapeErr = err
+			}
+			strBuilder = append(strBuilder, ch)
 			continue
 		}
 
 		strBuilder = append(strBuilder, l.char)
 	}
 
-	str := string(strBuilder)
+	endIndex := l.currentPosition
+	if tokenType == open{
+		// the expression inside never ends a statement on a newline
+		endIndex--
+		l.statementContexts = append(l.statementContexts, false)
+		l.interpolationDepths = append(l.interpolationDepths, len(l.statementContexts))
+	}
+	l.nextChar()
+	if escapeErr != nil{
+		return token.Token{Type: token.ERROR, Identifier: escapeErr.Error(), StartPosition: start-1, EndPosition: endIndex+1}
+	}
+
+	return token.Token{Type: tokenType, Identifier: string(strBuilder), StartPosition: start, EndPosition: endIndex}
+}
+
+func (l *Lexer) closesInterpolation() bool{
+	if len(l.interpolationDepths) == 0{
+		return false
+	}
+
+	return l.interpolationDepths[len(l.interpolationDepths)-1] == len(l.statementContexts)
+}
+
+// the character after a backslash, l.char is left on the last character of the escape
+func (l *Lexer) retrieveTheEscape() (rune, error){
+	switch l.char{
+	case 'n':
+		return '\n', nil
+	case 't':
+		return '\t', nil
+	case 'r':
+		return '\r', nil
+	case '\\':
+		return '\\', nil
+	case '"':
+		return '"', nil
+	case '$':
+		return '$', nil
+	case 'u':
+		return l.retrieveTheUnicodeEscape()
+	default:
+		return l.char, fmt.Errorf("invalid escape sequence \\%c", l.char)
+	}
+}
+
+// \u{...} with one to six hex digits
+func (l *Lexer) retrieveTheUnicodeEscape() (rune, error){
+	if l.peekChar() != '{'{
+		return utf8.RuneError, fmt.Errorf("invalid unicode escape, expected \\u{...}")
+	}
+	l.nextChar()
+
+	start := l.nextReadPosition
+	for l.peekChar() != '}' && l.peekChar() != '"' && l.nextReadPosition < len(l.input){
+		l.nextChar()
+	}
+	if l.peekChar() != '}'{
+		return utf8.RuneError, fmt.Errorf("invalid unicode escape, missing closing }")
+	}
+
+	digits := string(l.input[start:l.nextReadPosition])
+	l.nextChar()
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(value)){
+		return utf8.RuneError, fmt.Errorf("invalid unicode escape \\u{%s}", digits)
+	}
+
+	return rune(value), nil
+}
+
+// backtick strings keep every character as written, including newlines and backslashes
+func (l *Lexer) retrieveTheRawString() token.Token{
+	start := l.currentPosition+1
+	for {
+		l.nextChar()
+
+		if l.atEnd(){
+			return token.Token{Type: token.ERROR, Identifier: "unterminated raw string", StartPosition: start-1, EndPosition: l.currentPosition}
+		}
+
+		if l.char=='`'{
+			break
+		}
+	}
+
+	str := string(l.input[start:l.currentPosition])
 	endIndex := l.currentPosition
 	l.nextChar()
 	return token.Token{Type: token.STRING, Identifier: str, StartPosition: start, EndPosition: endIndex}
 }
 
+func (l *Lexer) atEnd() bool{
+	return l.currentPosition >= len(l.input)
+}
+
 func (l *Lexer) retrieveTheVariable() token.Token{
 	start := l.currentPosition
 
-	for (l.char>='0' && l.char<='9') || (l.char>='a' && l.char<='z') || (l.char>='A' && l.char<='Z'){
+	for isIdentifierChar(l.char){
 		l.nextChar()
 	}
 
@@ -117,15 +499,8 @@ func (l *Lexer) retrieveTheVariable() token.Token{
 func (l *Lexer) retrieveTheSign() token.Token{
 	var tk token.Token
 	switch l.char{
-	case '=':	
-		str:= string(l.char)
-		if l.peekChar() == '='{
-			l.nextChar()
-			str = str + string(l.char)
-			tk = token.Token{Type: token.DOUBLEEQUALTO, Identifier: str, StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
-		}else{
-			tk = token.Token{Type: token.EQUALTO, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
-		}
+	case '=':
+		tk = l.retrieveTheMultiCharSign(token.EQUALTO, map[rune]token.TokenType{'=': token.DOUBLEEQUALTO, '>': token.ARROW})
 	case '_':
 		tk = token.Token{Type: token.UNDERSCORE, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
 	case ';':
@@ -145,28 +520,33 @@ func (l *Lexer) retrieveTheSign() token.Token{
 	case ']':
 		tk = token.Token{Type: token.CLOSEBRACKET, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
 	case '<':
-		tk = token.Token{Type: token.OPENANGLE, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+		tk = l.retrieveTheMultiCharSign(token.OPENANGLE, map[rune]token.TokenType{'=': token.OPENANGLEEQUALTO, '<': token.LEFTSHIFT})
 	case '>':
-		tk = token.Token{Type: token.CLOSEANGLE, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+		tk = l.retrieveTheMultiCharSign(token.CLOSEANGLE, map[rune]token.TokenType{'=': token.CLOSEANGLEEQUALTO, '>': token.RIGHTSHIFT})
 	case ',':
 		tk = token.Token{Type: token.COMMA, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
 	case '+':
-		tk = token.Token{Type: token.PLUS, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+		tk = l.retrieveTheMultiCharSign(token.PLUS, map[rune]token.TokenType{'=': token.PLUSEQUALTO})
 	case '-':
-		tk = token.Token{Type: token.MINUS, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+		tk = l.retrieveTheMultiCharSign(token.MINUS, map[rune]token.TokenType{'=': token.MINUSEQUALTO})
 	case '/':
-		tk = token.Token{Type: token.DIVIDE, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+		tk = l.retrieveTheMultiCharSign(token.DIVIDE, map[rune]token.TokenType{'=': token.DIVIDEEQUALTO})
 	case '*':
-		tk = token.Token{Type: token.MULTIPLY, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+		tk = l.retrieveTheMultiCharSign(token.MULTIPLY, map[rune]token.TokenType{'=': token.MULTIPLYEQUALTO, '*': token.POWER})
 	case '!':
-		str := string(l.char)
-		if l.peekChar() == '='{
-			l.nextChar()
-			str = str + string(l.char)
-			tk = token.Token{Type: token.EXCLAMATIONEQUALTO, Identifier: str, StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
-		}else{
-			tk = token.Token{Type: token.EXCLAMATION, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
-		}
+		tk = l.retrieveTheMultiCharSign(token.EXCLAMATION, map[rune]token.TokenType{'=': token.EXCLAMATIONEQUALTO})
+	case '&':
+		tk = l.retrieveTheMultiCharSign(token.AMPERSAND, map[rune]token.TokenType{'&': token.AND})
+	case '|':
+		tk = l.retrieveTheMultiCharSign(token.PIPE, map[rune]token.TokenType{'|': token.OR})
+	case '%':
+		tk = token.Token{Type: token.MODULO, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+	case '^':
+		tk = token.Token{Type: token.CARET, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+	case '~':
+		tk = token.Token{Type: token.TILDE, Identifier: string(l.char), StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
+	case '.':
+		tk = l.retrieveTheEllipsis()
 	default:
 		if l.char == 0{
 			tk = token.Token{Type: token.EOF, Identifier: "", StartPosition: l.currentPosition, EndPosition: l.nextReadPosition}
@@ -178,6 +558,51 @@ func (l *Lexer) retrieveTheSign() token.Token{
 	return tk
 }
 
+// operators that can continue with a second character, like < followed by = or <
+func (l *Lexer) retrieveTheMultiCharSign(single token.TokenType, followers map[rune]token.TokenType) token.Token{
+	start := l.currentPosition
+	if tokenType, ok := followers[l.peekChar()]; ok{
+		l.nextChar()
+		return token.Token{Type: tokenType, Identifier: string(l.input[start:l.nextReadPosition]), StartPosition: start, EndPosition: l.nextReadPosition}
+	}
+
+	return token.Token{Type: single, Identifier: string(l.char), StartPosition: start, EndPosition: l.nextReadPosition}
+}
+
+// three dots are an ellipsis, anything else is a single dot
+func (l *Lexer) retrieveTheEllipsis() token.Token{
+	start := l.currentPosition
+	if l.peekChar() == '.' && l.peekCharAt(2) == '.'{
+		l.nextChar()
+		l.nextChar()
+		return token.Token{Type: token.ELLIPSIS, Identifier: "...", StartPosition: start, EndPosition: l.nextReadPosition}
+	}
+
+	return token.Token{Type: token.DOT, Identifier: string(l.char), StartPosition: start, EndPosition: l.nextReadPosition}
+}
+
+func isDigit(c rune) bool{
+	return c >= '0' && c <= '9'
+}
+
+// unicode letters and the underscore can start an identifier
+func isLetter(c rune) bool{
+	return c == '_' || unicode.IsLetter(c)
+}
+
+func isIdentifierChar(c rune) bool{
+	return isLetter(c) || unicode.IsDigit(c)
+}
+
+func isBasePrefix(c rune) bool{
+	switch c{
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+
+	return false
+}
+
 func isEscapeSequence(c rune) bool{
 	return  c==' ' || c=='\n' || c=='\t' || c=='\r'
 }
\ No newline at end of file
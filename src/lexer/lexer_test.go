@@ -8,7 +8,7 @@ import (
 )
 
 func TestNextToken(t * testing.T){
-	input := `let five=5;let ten=10;let add = fn(x,y){x+y;}let str = "this is a string";!-/*5;5<10>5; if(5<10){return true;}else{return false;}10==10;10!=9;[1,2];:`
+	input := `let five=5;let ten=10;let add = fn(x,y){x+y;}let str = "this is a string";!-/ *5;5<10>5; if(5<10){return true;}else{return false;}10==10;10!=9;[1,2];:`
 
 	tests:= []struct{
 		expectedType token.TokenType
@@ -105,4 +105,442 @@ func TestNextToken(t * testing.T){
 
 		fmt.Printf("tokenLiteral : %q\n", tt.expectedIdentifier)
 	}
-}
\ No newline at end of file
+}
+
+type expectedToken struct{
+	expectedType token.TokenType
+	expectedIdentifier string
+}
+
+// reads input token by token and compares each one with expected in order
+func checkTokens(t *testing.T, input string, expected []expectedToken){
+	t.Helper()
+
+	l := New(input)
+
+	for i, tt := range expected{
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType{
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Identifier != tt.expectedIdentifier{
+			t.Fatalf("tests[%d] - tokenIdentifier wrong. expected=%q, got=%q", i, tt.expectedIdentifier, tok.Identifier)
+		}
+	}
+}
+
+func TestLoopKeywords(t *testing.T){
+	input := `while(x){break;}for(;;){continue;}`
+
+	tests := []expectedToken{
+		{token.WHILE,"while"},
+		{token.OPENROUND,"("},
+		{token.VARIABLE,"x"},
+		{token.CLOSEROUND,")"},
+		{token.OPENBRACE,"{"},
+		{token.BREAK,"break"},
+		{token.SEMICOLON,";"},
+		{token.CLOSEBRACE,"}"},
+		{token.FOR,"for"},
+		{token.OPENROUND,"("},
+		{token.SEMICOLON,";"},
+		{token.SEMICOLON,";"},
+		{token.CLOSEROUND,")"},
+		{token.OPENBRACE,"{"},
+		{token.CONTINUE,"continue"},
+		{token.SEMICOLON,";"},
+		{token.CLOSEBRACE,"}"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestMatchTokens(t *testing.T){
+	input := `match(x){[a, ...r] => a, _ => 0}`
+
+	tests := []expectedToken{
+		{token.MATCH,"match"},
+		{token.OPENROUND,"("},
+		{token.VARIABLE,"x"},
+		{token.CLOSEROUND,")"},
+		{token.OPENBRACE,"{"},
+		{token.OPENBRACKET,"["},
+		{token.VARIABLE,"a"},
+		{token.COMMA,","},
+		{token.ELLIPSIS,"..."},
+		{token.VARIABLE,"r"},
+		{token.CLOSEBRACKET,"]"},
+		{token.ARROW,"=>"},
+		{token.VARIABLE,"a"},
+		{token.COMMA,","},
+		{token.UNDERSCORE,"_"},
+		{token.ARROW,"=>"},
+		{token.NUMBER,"0"},
+		{token.CLOSEBRACE,"}"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+
+	if tok := New("a..b").NextToken(); tok.Type != token.VARIABLE{
+		t.Fatalf("expected a variable, got=%q", tok.Type)
+	}
+}
+
+func TestInterpolatedStringTokens(t *testing.T){
+	input := `"a ${x} b ${ {"k": "${1}"}["k"] }" + "\${x}"`
+
+	tests := []expectedToken{
+		{token.STRINGHEAD,"a "},
+		{token.VARIABLE,"x"},
+		{token.STRINGMIDDLE," b "},
+		{token.OPENBRACE,"{"},
+		{token.STRING,"k"},
+		{token.COLON,":"},
+		{token.STRINGHEAD,""},
+		{token.NUMBER,"1"},
+		{token.STRINGTAIL,""},
+		{token.CLOSEBRACE,"}"},
+		{token.OPENBRACKET,"["},
+		{token.STRING,"k"},
+		{token.CLOSEBRACKET,"]"},
+		{token.STRINGTAIL,""},
+		{token.PLUS,"+"},
+		{token.STRING,"${x}"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestModuleTokens(t *testing.T){
+	input := `import "lib/math.dm" as m
+export let x = m.sqrt(4)`
+
+	tests := []expectedToken{
+		{token.IMPORT,"import"},
+		{token.STRING,"lib/math.dm"},
+		{token.AS,"as"},
+		{token.VARIABLE,"m"},
+		{token.SEMICOLON,"\n"},
+		{token.EXPORT,"export"},
+		{token.LET,"let"},
+		{token.VARIABLE,"x"},
+		{token.EQUALTO,"="},
+		{token.VARIABLE,"m"},
+		{token.DOT,"."},
+		{token.VARIABLE,"sqrt"},
+		{token.OPENROUND,"("},
+		{token.NUMBER,"4"},
+		{token.CLOSEROUND,")"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestAssignmentOperators(t *testing.T){
+	input := `x=1;x+=2;x-=3;x*=4;x/=5;x==x+`
+
+	tests := []expectedToken{
+		{token.VARIABLE,"x"},
+		{token.EQUALTO,"="},
+		{token.NUMBER,"1"},
+		{token.SEMICOLON,";"},
+		{token.VARIABLE,"x"},
+		{token.PLUSEQUALTO,"+="},
+		{token.NUMBER,"2"},
+		{token.SEMICOLON,";"},
+		{token.VARIABLE,"x"},
+		{token.MINUSEQUALTO,"-="},
+		{token.NUMBER,"3"},
+		{token.SEMICOLON,";"},
+		{token.VARIABLE,"x"},
+		{token.MULTIPLYEQUALTO,"*="},
+		{token.NUMBER,"4"},
+		{token.SEMICOLON,";"},
+		{token.VARIABLE,"x"},
+		{token.DIVIDEEQUALTO,"/="},
+		{token.NUMBER,"5"},
+		{token.SEMICOLON,";"},
+		{token.VARIABLE,"x"},
+		{token.DOUBLEEQUALTO,"=="},
+		{token.VARIABLE,"x"},
+		{token.PLUS,"+"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestLogicalOperators(t *testing.T){
+	input := `a&&b||c&d`
+
+	tests := []expectedToken{
+		{token.VARIABLE,"a"},
+		{token.AND,"&&"},
+		{token.VARIABLE,"b"},
+		{token.OR,"||"},
+		{token.VARIABLE,"c"},
+		{token.AMPERSAND,"&"},
+		{token.VARIABLE,"d"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestComparisonOperators(t *testing.T){
+	input := `a<=b>=c<d>e`
+
+	tests := []expectedToken{
+		{token.VARIABLE,"a"},
+		{token.OPENANGLEEQUALTO,"<="},
+		{token.VARIABLE,"b"},
+		{token.CLOSEANGLEEQUALTO,">="},
+		{token.VARIABLE,"c"},
+		{token.OPENANGLE,"<"},
+		{token.VARIABLE,"d"},
+		{token.CLOSEANGLE,">"},
+		{token.VARIABLE,"e"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestArithmeticOperators(t *testing.T){
+	input := `a%b**c*d&e|f^~g<<h>>i*=j`
+
+	tests := []expectedToken{
+		{token.VARIABLE,"a"},
+		{token.MODULO,"%"},
+		{token.VARIABLE,"b"},
+		{token.POWER,"**"},
+		{token.VARIABLE,"c"},
+		{token.MULTIPLY,"*"},
+		{token.VARIABLE,"d"},
+		{token.AMPERSAND,"&"},
+		{token.VARIABLE,"e"},
+		{token.PIPE,"|"},
+		{token.VARIABLE,"f"},
+		{token.CARET,"^"},
+		{token.TILDE,"~"},
+		{token.VARIABLE,"g"},
+		{token.LEFTSHIFT,"<<"},
+		{token.VARIABLE,"h"},
+		{token.RIGHTSHIFT,">>"},
+		{token.VARIABLE,"i"},
+		{token.MULTIPLYEQUALTO,"*="},
+		{token.VARIABLE,"j"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestFloatNumbers(t *testing.T){
+	input := `3.14 1e-9 2.5E+3 10 1. 2e`
+
+	tests := []expectedToken{
+		{token.FLOAT,"3.14"},
+		{token.FLOAT,"1e-9"},
+		{token.FLOAT,"2.5E+3"},
+		{token.NUMBER,"10"},
+		{token.NUMBER,"1"},
+		{token.DOT,"."},
+		{token.NUMBER,"2"},
+		{token.VARIABLE,"e"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestStringLiterals(t *testing.T){
+	tests := []struct{
+		input string
+		expectedType token.TokenType
+		expectedIdentifier string
+	}{
+		{`"a\"b"`, token.STRING, `a"b`},
+		{`"tab\tnew\nline\r"`, token.STRING, "tab\tnew\nline\r"},
+		{`"back\\slash"`, token.STRING, `back\slash`},
+		{`"\u{48}\u{e9}\u{1F600}"`, token.STRING, "H\u00e9\U0001F600"},
+		{"\"keeps\nnewlines\"", token.STRING, "keeps\nnewlines"},
+		{"`raw \\n ${x}\nsecond line`", token.STRING, "raw \\n ${x}\nsecond line"},
+		{"``", token.STRING, ""},
+		{`"no end`, token.ERROR, "unterminated string"},
+		{`"ends in backslash\`, token.ERROR, "unterminated string"},
+		{"`no end", token.ERROR, "unterminated raw string"},
+		{`"bad \q escape"`, token.ERROR, `invalid escape sequence \q`},
+		{`"\u{110000}"`, token.ERROR, `invalid unicode escape \u{110000}`},
+		{`"\u{}"`, token.ERROR, `invalid unicode escape \u{}`},
+		{`"\u41"`, token.ERROR, `invalid unicode escape, expected \u{...}`},
+		{`"\u{41"`, token.ERROR, `invalid unicode escape, missing closing }`},
+	}
+
+	for i, tt := range tests{
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType{
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Identifier != tt.expectedIdentifier{
+			t.Fatalf("tests[%d] - tokenIdentifier wrong. expected=%q, got=%q", i, tt.expectedIdentifier, tok.Identifier)
+		}
+
+		if next := l.NextToken(); next.Type != token.EOF{
+			t.Fatalf("tests[%d] - expected the string to consume the input, got=%q", i, next.Type)
+		}
+	}
+}
+
+func TestComments(t *testing.T){
+	input := `// leading note
+let x = 10; // trailing note
+/* block
+   comment */ x / 2;
+/* outer /* inner */ still outer */ x
+/* never closed`
+
+	tests := []struct{
+		expectedType token.TokenType
+		expectedIdentifier string
+		expectedComments []string
+	}{
+		{token.LET, "let", []string{"// leading note"}},
+		{token.VARIABLE, "x", nil},
+		{token.EQUALTO, "=", nil},
+		{token.NUMBER, "10", nil},
+		{token.SEMICOLON, ";", nil},
+		{token.VARIABLE, "x", []string{"// trailing note", "/* block\n   comment */"}},
+		{token.DIVIDE, "/", nil},
+		{token.NUMBER, "2", nil},
+		{token.SEMICOLON, ";", nil},
+		{token.VARIABLE, "x", []string{"/* outer /* inner */ still outer */"}},
+		{token.SEMICOLON, "\n", nil},
+		{token.ERROR, "unterminated block comment", nil},
+		{token.EOF, "", nil},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests{
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType{
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Identifier != tt.expectedIdentifier{
+			t.Fatalf("tests[%d] - tokenIdentifier wrong. expected=%q, got=%q", i, tt.expectedIdentifier, tok.Identifier)
+		}
+		if len(tok.Comments) != len(tt.expectedComments){
+			t.Fatalf("tests[%d] - wrong number of comments. expected=%q, got=%q", i, tt.expectedComments, tok.Comments)
+		}
+		for j, comment := range tt.expectedComments{
+			if tok.Comments[j] != comment{
+				t.Fatalf("tests[%d] - comment %d wrong. expected=%q, got=%q", i, j, comment, tok.Comments[j])
+			}
+		}
+	}
+}
+
+func TestIdentifiersAndNumberLiterals(t *testing.T){
+	input := `snake_case _private café 日本 x1 _ 0xFF 0o17 0b1010 1_000_000 1_000.5 0x1G`
+
+	tests := []expectedToken{
+		{token.VARIABLE,"snake_case"},
+		{token.VARIABLE,"_private"},
+		{token.VARIABLE,"café"},
+		{token.VARIABLE,"日本"},
+		{token.VARIABLE,"x1"},
+		{token.UNDERSCORE,"_"},
+		{token.NUMBER,"0xFF"},
+		{token.NUMBER,"0o17"},
+		{token.NUMBER,"0b1010"},
+		{token.NUMBER,"1_000_000"},
+		{token.FLOAT,"1_000.5"},
+		{token.NUMBER,"0x1G"},
+		{token.EOF,""},
+	}
+
+	checkTokens(t, input, tests)
+}
+
+func TestTokenPositions(t *testing.T){
+	input := "let a = 10;\n\tlet bé == \"hi\";\r\n`x\ny` a"
+
+	tests := []struct{
+		expectedIdentifier string
+		line, column, endLine, endColumn int
+	}{
+		{"let", 1, 1, 1, 4},
+		{"a", 1, 5, 1, 6},
+		{"=", 1, 7, 1, 8},
+		{"10", 1, 9, 1, 11},
+		{";", 1, 11, 1, 12},
+		{"let", 2, 2, 2, 5},
+		{"bé", 2, 6, 2, 8},
+		{"==", 2, 9, 2, 11},
+		{"hi", 2, 13, 2, 15},
+		{";", 2, 16, 2, 17},
+		{"x\ny", 3, 2, 4, 2},
+		{"a", 4, 4, 4, 5},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests{
+		tok := l.NextToken()
+
+		if tok.Identifier != tt.expectedIdentifier{
+			t.Fatalf("tests[%d] - tokenIdentifier wrong. expected=%q, got=%q", i, tt.expectedIdentifier, tok.Identifier)
+		}
+		if tok.Line != tt.line || tok.Column != tt.column{
+			t.Fatalf("tests[%d] - start wrong. expected=%d:%d, got=%d:%d", i, tt.line, tt.column, tok.Line, tok.Column)
+		}
+		if tok.EndLine != tt.endLine || tok.EndColumn != tt.endColumn{
+			t.Fatalf("tests[%d] - end wrong. expected=%d:%d, got=%d:%d", i, tt.endLine, tt.endColumn, tok.EndLine, tok.EndColumn)
+		}
+	}
+}
+
+func TestSemicolonInsertion(t *testing.T){
+	tests := []struct{
+		input string
+		expected []token.TokenType
+	}{
+		{"let a = 1\nlet b = 2\n", []token.TokenType{token.LET, token.VARIABLE, token.EQUALTO, token.NUMBER, token.SEMICOLON, token.LET, token.VARIABLE, token.EQUALTO, token.NUMBER, token.SEMICOLON}},
+		{"let a = 1;\n", []token.TokenType{token.LET, token.VARIABLE, token.EQUALTO, token.NUMBER, token.SEMICOLON}},
+		{"a +\nb", []token.TokenType{token.VARIABLE, token.PLUS, token.VARIABLE}},
+		{"a\n&& b", []token.TokenType{token.VARIABLE, token.AND, token.VARIABLE}},
+		{"f(1,\n2\n)", []token.TokenType{token.VARIABLE, token.OPENROUND, token.NUMBER, token.COMMA, token.NUMBER, token.CLOSEROUND}},
+		{"{\"a\": 1\n}", []token.TokenType{token.OPENBRACE, token.STRING, token.COLON, token.NUMBER, token.CLOSEBRACE}},
+		{"if(x){\n1\n}\nelse {2}", []token.TokenType{token.IF, token.OPENROUND, token.VARIABLE, token.CLOSEROUND, token.OPENBRACE, token.NUMBER, token.SEMICOLON, token.CLOSEBRACE, token.ELSE, token.OPENBRACE, token.NUMBER, token.CLOSEBRACE}},
+		{"while(x)\n{ break\n}", []token.TokenType{token.WHILE, token.OPENROUND, token.VARIABLE, token.CLOSEROUND, token.OPENBRACE, token.BREAK, token.SEMICOLON, token.CLOSEBRACE}},
+		{"x // note\ny", []token.TokenType{token.VARIABLE, token.SEMICOLON, token.VARIABLE}},
+		{"x /* spans\nlines */ y", []token.TokenType{token.VARIABLE, token.SEMICOLON, token.VARIABLE}},
+		{"x /* one line */\n* y", []token.TokenType{token.VARIABLE, token.MULTIPLY, token.VARIABLE}},
+		{"let x = 1\n; x", []token.TokenType{token.LET, token.VARIABLE, token.EQUALTO, token.NUMBER, token.SEMICOLON, token.VARIABLE}},
+		{"x // note\n;y", []token.TokenType{token.VARIABLE, token.SEMICOLON, token.VARIABLE}},
+	}
+
+	for i, tt := range tests{
+		l := New(tt.input)
+
+		for j, expected := range tt.expected{
+			tok := l.NextToken()
+			if tok.Type != expected{
+				t.Fatalf("tests[%d] - token %d wrong. expected=%q, got=%q", i, j, expected, tok.Type)
+			}
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF{
+			t.Fatalf("tests[%d] - expected eof, got=%q", i, tok.Type)
+		}
+	}
+}
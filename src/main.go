@@ -10,6 +10,14 @@ import (
 
 
 func main(){
+	// a file to run, otherwise the repl starts
+	if len(os.Args) > 1{
+		if err := repl.RunFile(os.Args[1]); err != nil{
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	user, err := user.Current()
 	if err != nil{
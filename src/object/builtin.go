@@ -1,6 +1,11 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
 
 var Builtins = []struct {
 	Name    string
@@ -119,6 +124,62 @@ var Builtins = []struct {
 		},
 		},
 	},
+	{
+		"int",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *Integer, *BigInteger:
+				return arg
+			case *Float:
+				// NaN fails both comparisons, 2^63 is the first float past the largest int
+				if !(arg.Value >= math.MinInt64 && arg.Value < -math.MinInt64) {
+					return newError("could not convert %s to an int", arg.Inspect())
+				}
+				// truncates towards zero
+				return &Integer{Value: int64(arg.Value)}
+			case *String:
+				value, err := strconv.ParseInt(arg.Value, 10, 64)
+				if err != nil {
+					return newError("could not convert %q to an int", arg.Value)
+				}
+				return &Integer{Value: value}
+			default:
+				return newError("argument to int not supported, got %s", args[0].Type())
+			}
+		},
+		},
+	},
+	{
+		"float",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *Float:
+				return arg
+			case *Integer:
+				return &Float{Value: float64(arg.Value)}
+			case *BigInteger:
+				value, _ := new(big.Float).SetInt(arg.Value).Float64()
+				return &Float{Value: value}
+			case *String:
+				value, err := strconv.ParseFloat(arg.Value, 64)
+				if err != nil {
+					return newError("could not convert %q to a float", arg.Value)
+				}
+				return &Float{Value: value}
+			default:
+				return newError("argument to float not supported, got %s", args[0].Type())
+			}
+		},
+		},
+	},
 }
 
 var builtins = map[string]*Builtin{
@@ -127,6 +188,8 @@ var builtins = map[string]*Builtin{
 	"first": GetBuiltinByName("first"),
 	"rest":  GetBuiltinByName("rest"),
 	"push":  GetBuiltinByName("push"),
+	"int":   GetBuiltinByName("int"),
+	"float": GetBuiltinByName("float"),
 }
 
 func GetBuiltinByName(name string) *Builtin {
@@ -0,0 +1,138 @@
+package object
+
+import "math/big"
+
+// implemented by values that compare by what they hold, a value without it
+// is only equal to itself
+type Equatable interface {
+	Equal(other Object) bool
+}
+
+// arrays and hashes compare element by element. a pair of them that is
+// already being compared further up counts as equal, so values that contain
+// themselves don't recurse forever
+func Equal(left, right Object) bool {
+	return equal(left, right, map[[2]Object]bool{})
+}
+
+func equal(left, right Object, comparing map[[2]Object]bool) bool {
+	if left == right {
+		return true
+	}
+
+	switch left := left.(type) {
+	case *Array:
+		return left.equal(right, comparing)
+	case *Hash:
+		return left.equal(right, comparing)
+	case Equatable:
+		return left.Equal(right)
+	}
+
+	return false
+}
+
+// numbers of different kinds are equal when they hold the same value, 1 == 1.0
+func (i *Integer) Equal(other Object) bool {
+	switch other := other.(type) {
+	case *Integer:
+		return i.Value == other.Value
+	case *Float:
+		return float64(i.Value) == other.Value
+	case *BigInteger:
+		return other.Value.Cmp(big.NewInt(i.Value)) == 0
+	}
+
+	return false
+}
+
+func (bi *BigInteger) Equal(other Object) bool {
+	switch other := other.(type) {
+	case *Integer:
+		return bi.Value.Cmp(big.NewInt(other.Value)) == 0
+	case *Float:
+		value, _ := new(big.Float).SetInt(bi.Value).Float64()
+		return value == other.Value
+	case *BigInteger:
+		return bi.Value.Cmp(other.Value) == 0
+	}
+
+	return false
+}
+
+func (f *Float) Equal(other Object) bool {
+	switch other := other.(type) {
+	case *Float:
+		return f.Value == other.Value
+	case *Integer, *BigInteger:
+		return other.(Equatable).Equal(f)
+	}
+
+	return false
+}
+
+func (b *Boolean) Equal(other Object) bool {
+	o, ok := other.(*Boolean)
+	return ok && b.Value == o.Value
+}
+
+func (n *Null) Equal(other Object) bool {
+	_, ok := other.(*Null)
+	return ok
+}
+
+func (s *String) Equal(other Object) bool {
+	o, ok := other.(*String)
+	return ok && s.Value == o.Value
+}
+
+func (a *Array) Equal(other Object) bool {
+	return a.equal(other, map[[2]Object]bool{})
+}
+
+func (a *Array) equal(other Object, comparing map[[2]Object]bool) bool {
+	o, ok := other.(*Array)
+	if !ok || len(a.Elements) != len(o.Elements) {
+		return false
+	}
+
+	pair := [2]Object{a, o}
+	if comparing[pair] {
+		return true
+	}
+	comparing[pair] = true
+
+	for i, element := range a.Elements {
+		if !equal(element, o.Elements[i], comparing) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (h *Hash) Equal(other Object) bool {
+	return h.equal(other, map[[2]Object]bool{})
+}
+
+func (h *Hash) equal(other Object, comparing map[[2]Object]bool) bool {
+	o, ok := other.(*Hash)
+	if !ok || len(h.Pairs) != len(o.Pairs) {
+		return false
+	}
+
+	pair := [2]Object{h, o}
+	if comparing[pair] {
+		return true
+	}
+	comparing[pair] = true
+
+	for key, hashPair := range h.Pairs {
+		otherPair, ok := o.Pairs[key]
+		if !ok || !equal(hashPair.Value, otherPair.Value, comparing) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -3,6 +3,10 @@ package object
 import (
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 
 	"github.com/singlaanish56/Compiler-in-go/code"
 )
@@ -16,12 +20,15 @@ type Object interface {
 
 const (
 	INTEGER_OBJ          = "INTEGER"
+	FLOAT_OBJ            = "FLOAT"
+	BIG_INTEGER_OBJ      = "BIG_INTEGER"
 	BOOLEAN_OBJ          = "BOOLEAN"
 	NULL_OBJ             = "NULL"
 	STRING_OBJ           = "STRING"
 	ARRAY_OBJ            = "ARRAY"
 	HASHPAIR_OBJ         = "HASHPAIR"
 	COMPILE_FUNCTION_OBJ = "COMPILE_FUNCTION"
+	CLOSURE_OBJ          = "CLOSURE"
 	BUILTIN_OBJ          = "BUILTIN"
 	ERROR_OBJ            = "ERROR"
 )
@@ -50,12 +57,49 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// only produced by a vm running with the big overflow policy, for values that do not fit an int64
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (bi *BigInteger) Type() ObjectType { return BIG_INTEGER_OBJ }
+func (bi *BigInteger) Inspect() string  { return bi.Value.String() }
+func (bi *BigInteger) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(bi.Value.String()))
+
+	return HashKey{Type: bi.Type(), Value: h.Sum64()}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	out := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(out, ".eIN") {
+		out += ".0"
+	}
+	return out
+}
+
+// a float holding a whole number hashes like the integer it equals, so 1 and
+// 1.0 are the same hash key, just like 1 == 1.0
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
 
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
-func (b *Boolean) Inspect() string  { return fmt.Sprintf("%d", b.Value) }
+func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
 func (b *Boolean) HashKey() HashKey {
 	if b.Value {
 		return HashKey{Type: b.Type(), Value: uint64(1)}
@@ -82,6 +126,9 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// arrays and hashes are reference values, binding one to another name or
+// passing it to a function shares it, so an index assignment through any of
+// them is visible through all of them. builtins like push and rest copy.
 type Array struct {
 	Elements []Object
 }
@@ -98,6 +145,7 @@ func (a *Array) Inspect() string {
 	return "[" + out + "]"
 }
 
+// see Array for the sharing semantics
 type Hash struct {
 	Pairs map[HashKey]HashPair
 }
@@ -111,15 +159,29 @@ func (h *Hash) Inspect() string {
 	return "{" + out + "}"
 }
 
+// the last NumberOfDefaults parameters can be left out of a call, a variadic
+// function takes any number of arguments past its parameters
 type CompiledFunction struct {
 	Instructions       code.Instructions
 	NumberOfLocals     int
 	NumberOfParameters int
+	NumberOfDefaults   int
+	Variadic           bool
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILE_FUNCTION_OBJ }
 func (cf *CompiledFunction) Inspect() string  { return fmt.Sprintf("CompiledFunction[%p]", cf) }
 
+// every function value at runtime is a closure, Free holds the values
+// captured from the enclosing scopes when the closure was created
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
+func (c *Closure) Inspect() string  { return fmt.Sprintf("Closure[%p]", c) }
+
 type BuiltInFunction func(args ...Object) Object
 type Builtin struct {
 	Fn BuiltInFunction
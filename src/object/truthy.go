@@ -0,0 +1,14 @@
+package object
+
+// the one truthiness table for the language. false and null are falsy and
+// every other value is truthy, including 0, 0.0, "", [] and {}
+func IsTruthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"github.com/singlaanish56/Compiler-in-go/ast"
+	"github.com/singlaanish56/Compiler-in-go/token"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// a problem found while parsing, the hint is an optional suggestion for fixing it
+type Diagnostic struct {
+	Severity Severity
+	Span     ast.Span
+	Message  string
+	Hint     string
+	Source   *token.Source
+}
+
+func (d *Diagnostic) Error() string {
+	out := d.Source.Format(d.Span.Start, d.Span.End, d.Severity.String()+": "+d.Message)
+	if d.Hint != "" {
+		out += "\nhint: " + d.Hint
+	}
+
+	return out
+}
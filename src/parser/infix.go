@@ -19,13 +19,76 @@ func (p *Parser) parseInfixExpression(leftExpression ast.Expression) ast.Express
 	return exp
 }
 
+// ** groups to the right, 2**3**2 is 2**(3**2)
+func (p *Parser) parsePowerExpression(leftExpression ast.Expression) ast.Expression{
+	exp := &ast.InfixExpression{
+		Token: p.currToken,
+		Operator: p.currToken.Identifier,
+		Left: leftExpression,
+	}
+
+	p.nextToken()
+	exp.Right = p.parseExpression(EXPONENT - 1)
+	return exp
+}
+
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression{
+	if left == nil{
+		return nil
+	}
+
+	tok := p.currToken
+
+	switch left := left.(type){
+	case *ast.Variable:
+		exp := &ast.AssignExpression{Token: tok, Name: left, Operator: tok.Identifier}
+		p.nextToken()
+		// one lower than its own precedence so a = b = c groups to the right
+		exp.Value = p.parseExpression(ASSIGN - 1)
+		return exp
+	case *ast.IndexExpression:
+		exp := &ast.IndexAssignExpression{Token: tok, Target: left, Operator: tok.Identifier}
+		p.nextToken()
+		exp.Value = p.parseExpression(ASSIGN - 1)
+		return exp
+	default:
+		// a left side that already failed to parse can have nil children, and its error is reported
+		if p.panicking{
+			return nil
+		}
+		p.addError(left.GetSpan(), "cannot assign to %s", left.String()).Hint = "only variables and index expressions like a[0] can be assigned to"
+		return nil
+	}
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression{
+	member := &ast.MemberExpression{Token: p.currToken, Left: left}
+
+	if !p.peekTokenIs(token.VARIABLE){
+		p.peekError(token.VARIABLE).Hint = "a . is followed by the name of an export, like m.name"
+		return nil
+	}
+	p.nextToken()
+	member.Property = &ast.Variable{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value: p.currToken.Identifier}
+
+	return member
+}
+
 func (p *Parser) parseArrayIndexExpression(left ast.Expression) ast.Expression{
 	indexExp := &ast.IndexExpression{Token: p.currToken, Left: left}
 
+	if p.peekTokenIs(token.COLON){
+		return p.parseSliceExpression(indexExp.Token, left, nil)
+	}
+
 	p.nextToken()
 
 	indexExp.Index = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COLON){
+		return p.parseSliceExpression(indexExp.Token, left, indexExp.Index)
+	}
+
 	if !p.checkPeek(token.CLOSEBRACKET){
 		return nil
 	}
@@ -33,6 +96,24 @@ func (p *Parser) parseArrayIndexExpression(left ast.Expression) ast.Expression{
 	return indexExp
 }
 
+// the start, if there is one, is already parsed and the colon is the peek token
+func (p *Parser) parseSliceExpression(tok token.Token, left ast.Expression, start ast.Expression) ast.Expression{
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+
+	p.nextToken()
+
+	if !p.peekTokenIs(token.CLOSEBRACKET){
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
+
+	if !p.checkPeek(token.CLOSEBRACKET){
+		return nil
+	}
+
+	return exp
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression{
 	exp := &ast.CallExpression{Token : p.currToken, Function : function}
 	exp.Arguments = p.parseExpressionList(token.CLOSEROUND)
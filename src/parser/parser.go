@@ -18,14 +18,18 @@ type Parser struct{
 	currToken token.Token
 	peekToken token.Token
 	lexer *lexer.Lexer
-	errors []error
+	diagnostics []*Diagnostic
+	// set by the first error in a statement, later errors are dropped until the parser resynchronizes
+	panicking bool
+	// how many { are open at the current token
+	braceDepth int
 
 	prefixParserMap map[token.TokenType]prefixParseFn
 	infixParserMap map[token.TokenType]infixParseFn
 }
 
 func New(lexer *lexer.Lexer) *Parser{
-	p := &Parser{lexer: lexer, errors: []error{}}
+	p := &Parser{lexer: lexer, diagnostics: []*Diagnostic{}}
 	p.nextToken()//sets the current token
 	p.nextToken()//sets the next token
 
@@ -33,11 +37,15 @@ func New(lexer *lexer.Lexer) *Parser{
 	p.infixParserMap = make(map[token.TokenType]infixParseFn)
 	p.addPrefix(token.VARIABLE, p.parseVariable)
 	p.addPrefix(token.NUMBER, p.parseNumber)
+	p.addPrefix(token.FLOAT, p.parseFloat)
 	p.addPrefix(token.STRING, p.parseStringExpression)
+	p.addPrefix(token.STRINGHEAD, p.parseInterpolatedString)
+	p.addPrefix(token.ERROR, p.parseLexerError)
 
 	p.addPrefix(token.MINUS, p.parsePrefixExpression)
 	p.addPrefix(token.PLUS, p.parsePrefixExpression)
 	p.addPrefix(token.EXCLAMATION, p.parsePrefixExpression)
+	p.addPrefix(token.TILDE, p.parsePrefixExpression)
 
 	p.addPrefix(token.TRUE, p.parseBooleanExpression)
 	p.addPrefix(token.FALSE, p.parseBooleanExpression)
@@ -48,6 +56,8 @@ func New(lexer *lexer.Lexer) *Parser{
 	p.addPrefix(token.OPENBRACE, p.parseHashMapExpression)
 	
 	p.addPrefix(token.IF, p.parseIfExpression)
+	p.addPrefix(token.MATCH, p.parseMatchExpression)
+	p.addPrefix(token.ELLIPSIS, p.parseSpreadExpression)
 
 	p.addPrefix(token.FUNCTION, p.parseFunctionExpression)
 
@@ -55,57 +65,151 @@ func New(lexer *lexer.Lexer) *Parser{
 	p.addInfix(token.MINUS, p.parseInfixExpression)
 	p.addInfix(token.MULTIPLY, p.parseInfixExpression)
 	p.addInfix(token.DIVIDE, p.parseInfixExpression)
+	p.addInfix(token.MODULO, p.parseInfixExpression)
+	p.addInfix(token.POWER, p.parsePowerExpression)
+	p.addInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.addInfix(token.PIPE, p.parseInfixExpression)
+	p.addInfix(token.CARET, p.parseInfixExpression)
+	p.addInfix(token.LEFTSHIFT, p.parseInfixExpression)
+	p.addInfix(token.RIGHTSHIFT, p.parseInfixExpression)
 
 	p.addInfix(token.DOUBLEEQUALTO, p.parseInfixExpression)
 	p.addInfix(token.EXCLAMATIONEQUALTO, p.parseInfixExpression)
 
+	p.addInfix(token.AND, p.parseInfixExpression)
+	p.addInfix(token.OR, p.parseInfixExpression)
+
 	p.addInfix(token.OPENANGLE, p.parseInfixExpression)
 	p.addInfix(token.CLOSEANGLE, p.parseInfixExpression)
+	p.addInfix(token.OPENANGLEEQUALTO, p.parseInfixExpression)
+	p.addInfix(token.CLOSEANGLEEQUALTO, p.parseInfixExpression)
 	p.addInfix(token.OPENBRACKET, p.parseArrayIndexExpression)
 
 	p.addInfix(token.OPENROUND, p.parseCallExpression)
+	p.addInfix(token.DOT, p.parseMemberExpression)
+
+	p.addInfix(token.EQUALTO, p.parseAssignExpression)
+	p.addInfix(token.PLUSEQUALTO, p.parseAssignExpression)
+	p.addInfix(token.MINUSEQUALTO, p.parseAssignExpression)
+	p.addInfix(token.MULTIPLYEQUALTO, p.parseAssignExpression)
+	p.addInfix(token.DIVIDEEQUALTO, p.parseAssignExpression)
 	return p
 }
 
 func (p *Parser) ParserProgram() *ast.AstRootNode{
-	rootNode := &ast.AstRootNode{Statements: []ast.Statement{}}
+	rootNode := &ast.AstRootNode{Statements: []ast.Statement{}, Source: p.lexer.Source()}
+	start := p.currToken
 	for p.currToken.Type != token.EOF{
-		stmt := p.parseStatement()
+		stmt := p.parseRecoveringStatement()
 		if stmt != nil{
 			rootNode.Statements = append(rootNode.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	rootNode.SetSpan(ast.Span{Start: start.Pos(), End: start.Pos()})
+	if len(rootNode.Statements) > 0{
+		rootNode.SetSpan(ast.Span{Start: rootNode.Statements[0].GetSpan().Start, End: rootNode.Statements[len(rootNode.Statements)-1].GetSpan().End})
+	}
 	return rootNode
 }
 
+// a statement that failed to parse is skipped up to the next statement boundary,
+// so one mistake is reported once instead of as a cascade of errors
+func (p *Parser) parseRecoveringStatement() ast.Statement{
+	depth := p.braceDepth
+	if p.currTokenIs(token.OPENBRACE){
+		depth--
+	}
+
+	stmt := p.parseStatement()
+	if p.panicking{
+		p.synchronize(depth)
+		p.panicking = false
+	}
+
+	return stmt
+}
+
+// skips any braces the broken statement opened, then stops on a semicolon
+// or before a token that starts a statement or closes the enclosing block
+func (p *Parser) synchronize(depth int){
+	for !p.currTokenIs(token.EOF){
+		if p.braceDepth <= depth{
+			if p.currTokenIs(token.SEMICOLON){
+				return
+			}
+
+			switch p.peekToken.Type{
+			case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE, token.IMPORT, token.EXPORT, token.CLOSEBRACE, token.EOF:
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
 func (p* Parser) parseStatement() ast.Statement{
+	start := p.currToken
+
+	var stmt ast.Statement
 	switch p.currToken.Type{
 	case token.LET:
-		return p.parseLetStatement()
+		stmt = p.parseLetStatement()
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
+	case token.WHILE:
+		stmt = p.parseWhileStatement()
+	case token.FOR:
+		stmt = p.parseForStatement()
+	case token.BREAK:
+		stmt = p.parseBreakStatement()
+	case token.CONTINUE:
+		stmt = p.parseContinueStatement()
+	case token.IMPORT:
+		stmt = p.parseImportStatement()
+	case token.EXPORT:
+		stmt = p.parseExportStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	if stmt != nil{
+		stmt.SetSpan(p.spanFrom(start))
 	}
+	return stmt
 }
  
 func (p *Parser) parseLetStatement() ast.Statement{
 	letstmt := & ast.LetStatement{Token: p.currToken}
 
-	if !p.checkPeek(token.VARIABLE){
+	if p.peekTokenIs(token.OPENBRACKET) || p.peekTokenIs(token.OPENBRACE){
+		p.nextToken()
+		letstmt.Pattern = p.parsePattern(true)
+		if letstmt.Pattern == nil{
+			return nil
+		}
+	}else if !p.peekTokenIs(token.VARIABLE){
+		p.peekError(token.VARIABLE).Hint = "give the variable a name, like let x = 1;"
 		return nil
+	}else{
+		p.nextToken()
+		letstmt.Variable = &ast.Variable{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value:p.currToken.Identifier}
 	}
 
-	letstmt.Variable = &ast.Variable{Token: p.currToken, Value:p.currToken.Identifier}
-
-	if !p.checkPeek(token.EQUALTO){
+	if !p.peekTokenIs(token.EQUALTO){
+		p.peekError(token.EQUALTO).Hint = "a let statement needs a value, like let x = 1;"
 		return nil
 	}
+	p.nextToken()
 
 	p.nextToken()
 
 	letstmt.Value = p.parseExpression(LOWEST)
+
+	if fn, ok := letstmt.Value.(*ast.FunctionExpression); ok && letstmt.Variable != nil{
+		fn.Name = letstmt.Variable.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON){
 		p.nextToken()
 	}
@@ -113,29 +217,248 @@ func (p *Parser) parseLetStatement() ast.Statement{
 	return letstmt
 }
 
+func (p *Parser) parseImportStatement() ast.Statement{
+	importstmt := &ast.ImportStatement{Token: p.currToken}
+
+	if p.braceDepth > 0{
+		p.addError(ast.TokenSpan(p.currToken), "import is only allowed at the top level of a file")
+		return nil
+	}
+
+	if !p.checkPeek(token.STRING){
+		return nil
+	}
+	importstmt.Path = &ast.StringLiteral{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value: p.currToken.Identifier}
+
+	if !p.peekTokenIs(token.AS){
+		p.peekError(token.AS).Hint = fmt.Sprintf("name the module, like import %q as m;", importstmt.Path.Value)
+		return nil
+	}
+	p.nextToken()
+
+	if !p.checkPeek(token.VARIABLE){
+		return nil
+	}
+	importstmt.Alias = &ast.Variable{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value: p.currToken.Identifier}
+
+	if p.peekTokenIs(token.SEMICOLON){
+		p.nextToken()
+	}
+
+	return importstmt
+}
+
+// only a let with a single name can be exported, the name is what importers see
+func (p *Parser) parseExportStatement() ast.Statement{
+	if p.braceDepth > 0{
+		p.addError(ast.TokenSpan(p.currToken), "export is only allowed at the top level of a file")
+		return nil
+	}
+
+	if !p.peekTokenIs(token.LET){
+		p.peekError(token.LET).Hint = "only let statements can be exported, like export let x = 1;"
+		return nil
+	}
+	p.nextToken()
+
+	letstmt, ok := p.parseLetStatement().(*ast.LetStatement)
+	if !ok{
+		return nil
+	}
+
+	if letstmt.Pattern != nil{
+		p.addError(letstmt.Pattern.GetSpan(), "cannot export a destructuring let").Hint = "export each name with its own let"
+		return nil
+	}
+
+	letstmt.Exported = true
+	return letstmt
+}
+
 func (p *Parser) parseReturnStatement() ast.Statement{
 	returnstmt := &ast.ReturnStatement{Token: p.currToken}
 
+	// a bare return has no value
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.CLOSEBRACE) || p.peekTokenIs(token.EOF){
+		if p.peekTokenIs(token.SEMICOLON){
+			p.nextToken()
+		}
+		return returnstmt
+	}
+
 	p.nextToken()
 
 	returnstmt.Value = p.parseExpression(LOWEST)
 
-	for !p.currTokenIs(token.SEMICOLON){
+	if p.peekTokenIs(token.SEMICOLON){
 		p.nextToken()
 	}
 
 	return returnstmt
 }
 
+func (p *Parser) parseWhileStatement() ast.Statement{
+	whilestmt := &ast.WhileStatement{Token: p.currToken}
+
+	if !p.checkPeek(token.OPENROUND){
+		return nil
+	}
+
+	p.nextToken()
+
+	whilestmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.checkPeek(token.CLOSEROUND){
+		return nil
+	}
+
+	if !p.checkPeek(token.OPENBRACE){
+		return nil
+	}
+
+	whilestmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON){
+		p.nextToken()
+	}
+
+	return whilestmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement{
+	forstmt := &ast.ForStatement{Token: p.currToken}
+
+	if !p.checkPeek(token.OPENROUND){
+		return nil
+	}
+
+	p.nextToken()
+
+	if !p.currTokenIs(token.SEMICOLON){
+		forstmt.Init = p.parseStatement()
+		if forstmt.Init == nil{
+			return nil
+		}
+
+		if !p.currTokenIs(token.SEMICOLON){
+			p.addError(ast.TokenSpan(p.currToken), "expected the token to be %s, got %s", token.SEMICOLON, p.currToken.Type)
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(token.SEMICOLON){
+		p.nextToken()
+		forstmt.Condition = p.parseExpression(LOWEST)
+	}
+
+	if !p.checkPeek(token.SEMICOLON){
+		return nil
+	}
+
+	if !p.peekTokenIs(token.CLOSEROUND){
+		p.nextToken()
+		forstmt.Update = p.parseExpression(LOWEST)
+	}
+
+	if !p.checkPeek(token.CLOSEROUND){
+		return nil
+	}
+
+	if !p.checkPeek(token.OPENBRACE){
+		return nil
+	}
+
+	forstmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON){
+		p.nextToken()
+	}
+
+	return forstmt
+}
+
+func (p *Parser) parseBreakStatement() ast.Statement{
+	breakstmt := &ast.BreakStatement{Token: p.currToken}
+
+	if p.peekTokenIs(token.SEMICOLON){
+		p.nextToken()
+	}
+
+	return breakstmt
+}
+
+func (p *Parser) parseContinueStatement() ast.Statement{
+	continuestmt := &ast.ContinueStatement{Token: p.currToken}
+
+	if p.peekTokenIs(token.SEMICOLON){
+		p.nextToken()
+	}
+
+	return continuestmt
+}
+
 func (p *Parser) parseExpressionStatement() ast.Statement{
 	st := &ast.ExpressionStatement{Token: p.currToken}
 	st.Expression = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COMMA){
+		return p.parseParallelAssignStatement(st.Expression)
+	}
+
 	if p.peekTokenIs(token.SEMICOLON){
 		p.nextToken()
 	}
 	return st
 }
 
+// the first target is already parsed, the others stop before the = so it isn't taken as an assignment
+func (p *Parser) parseParallelAssignStatement(first ast.Expression) ast.Statement{
+	targets := []ast.Expression{first}
+	for p.peekTokenIs(token.COMMA){
+		p.nextToken()
+		p.nextToken()
+		targets = append(targets, p.parseExpression(ASSIGN))
+	}
+
+	for _, target := range targets{
+		switch target.(type){
+		case *ast.Variable, *ast.IndexExpression:
+		case nil:
+			return nil
+		default:
+			// a target that already failed to parse can have nil children, and its error is reported
+			if p.panicking{
+				return nil
+			}
+			p.addError(target.GetSpan(), "cannot assign to %s", target.String()).Hint = "only variables and index expressions like a[0] can be assigned to"
+			return nil
+		}
+	}
+
+	if !p.checkPeek(token.EQUALTO){
+		return nil
+	}
+
+	stmt := &ast.ParallelAssignStatement{Token: p.currToken, Targets: targets}
+	p.nextToken()
+	stmt.Values = append(stmt.Values, p.parseExpression(LOWEST))
+	for p.peekTokenIs(token.COMMA){
+		p.nextToken()
+		p.nextToken()
+		stmt.Values = append(stmt.Values, p.parseExpression(LOWEST))
+	}
+
+	if len(stmt.Values) != len(stmt.Targets){
+		p.addError(ast.Span{Start: first.GetSpan().Start, End: p.currToken.EndPos()}, "assignment has %d targets but %d values", len(stmt.Targets), len(stmt.Values))
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON){
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement{
 	bexp := &ast.BlockStatement{Token : p.currToken}
 	bexp.Statements = []ast.Statement{}
@@ -143,24 +466,29 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement{
 	p.nextToken()
 
 	for !p.currTokenIs(token.CLOSEBRACE) && !p.currTokenIs(token.EOF){
-		st := p.parseStatement()
+		st := p.parseRecoveringStatement()
 		if st != nil{
 			bexp.Statements = append(bexp.Statements, st)
 		}
 		p.nextToken()
 	}
 
+	bexp.SetSpan(p.spanFrom(bexp.Token))
 	return bexp
 }
 
 func (p *Parser) parseExpression(precendence int) ast.Expression{
+	 start := p.currToken
 	 prefixFn := p.prefixParserMap[p.currToken.Type]
 	 if prefixFn == nil{
-		p.errors = append(p.errors, fmt.Errorf("no prefix parse function for %s found", p.currToken.Type))
+		p.addError(ast.TokenSpan(p.currToken), "no prefix parse function for %s found", p.currToken.Type).Hint = fmt.Sprintf("%q cannot start an expression", p.currToken.Identifier)
 		return nil
 	 }
 
 	 leftExpression := prefixFn()
+	 if leftExpression != nil{
+		leftExpression.SetSpan(p.spanFrom(start))
+	 }
 
 	 for !p.peekTokenIs(token.SEMICOLON) && precendence < p.peekPrecedence(){
 		infixFn := p.infixParserMap[p.peekToken.Type]
@@ -169,6 +497,9 @@ func (p *Parser) parseExpression(precendence int) ast.Expression{
 		}
 		p.nextToken()
 		leftExpression = infixFn(leftExpression)
+		if leftExpression != nil{
+			leftExpression.SetSpan(p.spanFrom(start))
+		}
 	 }
 
 	 return leftExpression
@@ -3,16 +3,36 @@ package parser
 import (
 	"fmt"
 
+	"github.com/singlaanish56/Compiler-in-go/ast"
 	"github.com/singlaanish56/Compiler-in-go/token"
 )
 
+// the error severity diagnostics as plain errors
 func (p *Parser) Errors() []error{
-	return p.errors
+	errors := []error{}
+	for _, d := range p.diagnostics{
+		if d.Severity == SeverityError{
+			errors = append(errors, d)
+		}
+	}
+
+	return errors
+}
+
+func (p *Parser) Diagnostics() []*Diagnostic{
+	return p.diagnostics
 }
 
 func (p *Parser) nextToken(){
 	p.currToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+
+	switch p.currToken.Type{
+	case token.OPENBRACE:
+		p.braceDepth++
+	case token.CLOSEBRACE:
+		p.braceDepth--
+	}
 }
 
 func (p *Parser) checkPeek(tokenType token.TokenType) bool{
@@ -29,9 +49,32 @@ func (p *Parser) peekTokenIs(tokenType token.TokenType) bool{
 	return p.peekToken.Type == tokenType
 }
 
-func (p *Parser) peekError(tokenType token.TokenType){
-	err := fmt.Errorf("expected the next token to be %s, got %s", tokenType, p.peekToken.Type)
-	p.errors = append(p.errors, err)
+func (p *Parser) peekError(tokenType token.TokenType) *Diagnostic{
+	d := p.addError(ast.TokenSpan(p.peekToken), "expected the next token to be %s, got %s", tokenType, p.peekToken.Type)
+	switch tokenType{
+	case token.CLOSEROUND, token.CLOSEBRACKET, token.CLOSEBRACE:
+		d.Hint = fmt.Sprintf("check for a missing %s", tokenType)
+	}
+
+	return d
+}
+
+// errors point at a range of the source so they can be printed with the line they came from,
+// the returned diagnostic can be given a hint
+func (p *Parser) addError(span ast.Span, format string, a ...any) *Diagnostic{
+	d := &Diagnostic{Severity: SeverityError, Span: span, Message: fmt.Sprintf(format, a...), Source: p.lexer.Source()}
+	if p.panicking{
+		return d
+	}
+
+	p.diagnostics = append(p.diagnostics, d)
+	p.panicking = true
+	return d
+}
+
+// from the start token up to and including the current one
+func (p *Parser) spanFrom(start token.Token) ast.Span{
+	return ast.Span{Start: start.Pos(), End: p.currToken.EndPos()}
 }
 
 func (p *Parser) currTokenIs(tokenType token.TokenType) bool{
@@ -40,8 +83,7 @@ func (p *Parser) currTokenIs(tokenType token.TokenType) bool{
 
 func (p *Parser) addPrefix(tokenType token.TokenType, fn prefixParseFn){
 	if _, exists := p.prefixParserMap[tokenType]; exists{
-		err := fmt.Errorf("prefix function already exists for token type %s", tokenType)
-		p.errors = append(p.errors, err)
+		p.addError(ast.Span{}, "prefix function already exists for token type %s", tokenType)
 		return
 	}
 
@@ -50,8 +92,7 @@ func (p *Parser) addPrefix(tokenType token.TokenType, fn prefixParseFn){
 
 func (p *Parser) addInfix(tokenType token.TokenType, fn infixParseFn){
 	if _, exists := p.infixParserMap[tokenType]; exists{
-		err := fmt.Errorf("infix function already exists for token type %s", tokenType)
-		p.errors = append(p.errors, err)
+		p.addError(ast.Span{}, "infix function already exists for token type %s", tokenType)
 		return
 	}
 
@@ -75,26 +116,51 @@ func (p *Parser) peekPrecedence() int{
 }
 
 var precendences = map[token.TokenType]int{
+	token.EQUALTO: ASSIGN,
+	token.PLUSEQUALTO: ASSIGN,
+	token.MINUSEQUALTO: ASSIGN,
+	token.MULTIPLYEQUALTO: ASSIGN,
+	token.DIVIDEEQUALTO: ASSIGN,
+	token.OR: LOGICALOR,
+	token.AND: LOGICALAND,
 	token.DOUBLEEQUALTO: EQUALS,
 	token.EXCLAMATIONEQUALTO : EQUALS,
 	token.OPENANGLE: LESSGREATER,
 	token.CLOSEANGLE: LESSGREATER,
+	token.OPENANGLEEQUALTO: LESSGREATER,
+	token.CLOSEANGLEEQUALTO: LESSGREATER,
 	token.PLUS: SUM,
 	token.MINUS: SUM,
 	token.MULTIPLY: PRODUCT,
 	token.DIVIDE: PRODUCT,
+	token.MODULO: PRODUCT,
+	token.POWER: EXPONENT,
+	token.PIPE: BITOR,
+	token.CARET: BITXOR,
+	token.AMPERSAND: BITAND,
+	token.LEFTSHIFT: SHIFT,
+	token.RIGHTSHIFT: SHIFT,
 	token.OPENBRACKET: INDEX,
 	token.OPENROUND: CALL,
+	token.DOT: INDEX,
 }
 
 const (
 	_int = iota
 	LOWEST
+	ASSIGN
+	LOGICALOR
+	LOGICALAND
 	EQUALS
 	LESSGREATER
+	BITOR
+	BITXOR
+	BITAND
+	SHIFT
 	SUM
 	PRODUCT
 	PREFIX
+	EXPONENT //binds tighter than a prefix operator, -2**2 is -(2**2)
 	CALL
 	INDEX
 )
\ No newline at end of file
@@ -123,6 +123,57 @@ func TestParserIndexExpression(t *testing.T){
 	}
 }
 
+func TestSliceExpression(t *testing.T){
+	tests := []struct{
+		input string
+		start string
+		end string
+		expected string
+	}{
+		{"a[1:3]", "1", "3", "(a[1:3])"},
+		{"a[:n]", "", "n", "(a[:n])"},
+		{"a[n:]", "n", "", "(a[n:])"},
+		{"a[:]", "", "", "(a[:])"},
+		{"a[-2:len(a) - 1]", "(-2)", "(len(a)-1)", "(a[(-2):(len(a)-1)])"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		prog := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		st := prog.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := st.Expression.(*ast.SliceExpression)
+		if !ok{
+			t.Fatalf("expected a slice expression, got=%T", st.Expression)
+		}
+
+		if !testIdentifier(t, exp.Left, "a"){
+			return
+		}
+
+		for _, bound := range []struct{
+			name string
+			value ast.Expression
+			expected string
+		}{{"start", exp.Start, tt.start}, {"end", exp.End, tt.end}}{
+			got := ""
+			if bound.value != nil{
+				got = bound.value.String()
+			}
+			if got != bound.expected{
+				t.Errorf("wrong %s for %q, want=%q, got=%q", bound.name, tt.input, bound.expected, got)
+			}
+		}
+
+		if exp.String() != tt.expected{
+			t.Errorf("wrong string, want=%q, got=%q", tt.expected, exp.String())
+		}
+	}
+}
+
 func TestHashLiteral(t *testing.T){
  input := `{"one":1,"second":2,"third":3}`
 
@@ -242,6 +293,275 @@ func TestFunctionParamters(t *testing.T){
 	}
 }
 
+func TestFunctionDefaultsAndRest(t *testing.T){
+	tests := []struct{
+		input string
+		expectedParams []string
+		expectedDefaults []string
+		expectedRest string
+		expected string
+	}{
+		{"fn(x, y = 10){}", []string{"x", "y"}, []string{"", "10"}, "", "fn(x,y=10){}"},
+		{"fn(first, ...others){}", []string{"first"}, []string{""}, "others", "fn(first,...others){}"},
+		{"fn(...all){}", []string{}, []string{}, "all", "fn(...all){}"},
+		{"fn(a, b = a * 2, ...r){}", []string{"a", "b"}, []string{"", "(a*2)"}, "r", "fn(a,b=(a*2),...r){}"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		prog := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		fn := prog.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionExpression)
+
+		if len(fn.Parameters) != len(tt.expectedParams){
+			t.Fatalf("the length of the parameters not as expected=%d, got=%d", len(tt.expectedParams), len(fn.Parameters))
+		}
+
+		for i, param := range fn.Parameters{
+			testLiteralExpression(t, param, tt.expectedParams[i])
+
+			got := ""
+			if fn.Defaults[i] != nil{
+				got = fn.Defaults[i].String()
+			}
+			if got != tt.expectedDefaults[i]{
+				t.Errorf("wrong default for %s, want=%q, got=%q", param.Value, tt.expectedDefaults[i], got)
+			}
+		}
+
+		rest := ""
+		if fn.Rest != nil{
+			rest = fn.Rest.Value
+		}
+		if rest != tt.expectedRest{
+			t.Errorf("wrong rest parameter, want=%q, got=%q", tt.expectedRest, rest)
+		}
+
+		if fn.String() != tt.expected{
+			t.Errorf("wrong string, want=%q, got=%q", tt.expected, fn.String())
+		}
+	}
+}
+
+func TestSpreadExpression(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"f(...args)", "f(...args)"},
+		{"f(1, ...a, ...b[0])", "f(1,...a,...(b[0]))"},
+		{"[...a, 2, ...g(x)]", "[...a,2,...g(x)]"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		prog := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		if prog.String() != tt.expected{
+			t.Errorf("wrong string, want=%q, got=%q", tt.expected, prog.String())
+		}
+	}
+}
+
+func TestParameterErrors(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"fn(x = 1, y){}", "parameter y needs a default, it follows one that has a default"},
+		{"fn(...r, y){}", "the rest parameter has to be the last one"},
+		{"fn(...r = 1){}", "the rest parameter has to be the last one"},
+		{"fn(x, ...){}", "expected the next token to be var, got )"},
+		{"fn(1){}", "expected the next token to be var, got int"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		p.ParserProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0{
+			t.Fatalf("expected an error for %q", tt.input)
+		}
+
+		if diagnostics[0].Message != tt.expected{
+			t.Errorf("wrong error for %q, want=%q, got=%q", tt.input, tt.expected, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestInterpolatedString(t *testing.T){
+	tests := []struct{
+		input string
+		parts []string
+	}{
+		{`"user ${name} has ${len(items)} items"`, []string{"user ", "name", " has ", "len(items)", " items"}},
+		{`"${a + b}"`, []string{"", "(a+b)", ""}},
+		{`"${a}${b}"`, []string{"", "a", "", "b", ""}},
+		{`"outer ${"inner ${x}"}"`, []string{"outer ", "inner ${x}", ""}},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		prog := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		st := prog.Statements[0].(*ast.ExpressionStatement)
+		str, ok := st.Expression.(*ast.InterpolatedString)
+		if !ok{
+			t.Fatalf("expected an interpolated string, got=%T", st.Expression)
+		}
+
+		if len(str.Parts) != len(tt.parts){
+			t.Fatalf("wrong number of parts for %q, want=%d, got=%d", tt.input, len(tt.parts), len(str.Parts))
+		}
+
+		for i, part := range str.Parts{
+			if part.String() != tt.parts[i]{
+				t.Errorf("wrong part %d for %q, want=%q, got=%q", i, tt.input, tt.parts[i], part.String())
+			}
+		}
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{`"a ${} b"`, "expected an expression inside ${}"},
+		{`"a ${1 2}"`, "expected the next token to be }, got int"},
+		{`"a ${x`, "expected the next token to be }, got eof"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		p.ParserProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0{
+			t.Fatalf("expected an error for %q", tt.input)
+		}
+
+		if diagnostics[0].Message != tt.expected{
+			t.Errorf("wrong error for %q, want=%q, got=%q", tt.input, tt.expected, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestModuleStatements(t *testing.T){
+	input := `import "lib/math.dm" as m
+export let square = fn(x){ x * x }
+let y = m.square(m.pi)`
+
+	p := New(lexer.New(input))
+	prog := p.ParserProgram()
+	if len(p.Errors()) != 0{
+		t.Fatalf("Parser has errors: %v", p.Errors())
+	}
+
+	if len(prog.Statements) != 3{
+		t.Fatalf("expected 3 statements, got=%d", len(prog.Statements))
+	}
+
+	imp, ok := prog.Statements[0].(*ast.ImportStatement)
+	if !ok{
+		t.Fatalf("expected an import statement, got=%T", prog.Statements[0])
+	}
+	if imp.Path.Value != "lib/math.dm" || imp.Alias.Value != "m"{
+		t.Errorf("wrong import, got=%s", imp.String())
+	}
+
+	export, ok := prog.Statements[1].(*ast.LetStatement)
+	if !ok || !export.Exported{
+		t.Fatalf("expected an exported let statement, got=%s", prog.Statements[1].String())
+	}
+	if fn, ok := export.Value.(*ast.FunctionExpression); !ok || fn.Name != "square"{
+		t.Errorf("expected the exported function to be named square, got=%s", export.Value.String())
+	}
+
+	let := prog.Statements[2].(*ast.LetStatement)
+	if let.Exported{
+		t.Errorf("let y should not be exported")
+	}
+	call, ok := let.Value.(*ast.CallExpression)
+	if !ok{
+		t.Fatalf("expected a call expression, got=%T", let.Value)
+	}
+	if _, ok := call.Function.(*ast.MemberExpression); !ok{
+		t.Fatalf("expected the function to be a member expression, got=%T", call.Function)
+	}
+
+	expected := `import "lib/math.dm" as m;export let square = fn(x){(x*x)};let y = m.square(m.pi);`
+	if prog.String() != expected{
+		t.Errorf("wrong string, want=%q, got=%q", expected, prog.String())
+	}
+}
+
+func TestModuleErrors(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{`import m`, "expected the next token to be str, got var"},
+		{`import "m.dm"`, "expected the next token to be as, got eof"},
+		{`import "m.dm" as 1`, "expected the next token to be var, got int"},
+		{`fn(){ import "m.dm" as m }`, "import is only allowed at the top level of a file"},
+		{`if(true){ export let x = 1 }`, "export is only allowed at the top level of a file"},
+		{`export x`, "expected the next token to be let, got var"},
+		{`export let [a, b] = [1, 2]`, "cannot export a destructuring let"},
+		{`m.1`, "expected the next token to be var, got int"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		p.ParserProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0{
+			t.Fatalf("expected an error for %q", tt.input)
+		}
+
+		if diagnostics[0].Message != tt.expected{
+			t.Errorf("wrong error for %q, want=%q, got=%q", tt.input, tt.expected, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestFunctionExpressionWithName(t *testing.T){
+	input := `let myFunction = fn(){};`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParserProgram()
+
+	if len(prog.Statements) != 1{
+		t.Fatalf("the number of statements not as expected, got=%d", len(prog.Statements))
+	}
+
+	st, ok := prog.Statements[0].(*ast.LetStatement)
+	if !ok{
+		t.Fatalf("the statement is not a let statement, got=%T", prog.Statements[0])
+	}
+
+	fn, ok := st.Value.(*ast.FunctionExpression)
+	if !ok{
+		t.Fatalf("the value is not a function expression, got=%T", st.Value)
+	}
+
+	if fn.Name != "myFunction"{
+		t.Errorf("the function name is not as expected=%q, got=%q", "myFunction", fn.Name)
+	}
+}
+
 func  TestIfExpression(t *testing.T){
 	tests := []struct{
 		input string
@@ -307,6 +627,752 @@ func  TestIfExpression(t *testing.T){
 	}
 }
 
+func TestElseIfExpression(t *testing.T){
+	input := "if(x<y){x}else if(x>y){y}else if(x==y){z}else{w}"
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParserProgram()
+	if len(p.Errors()) != 0{
+		t.Fatalf("Parser has errors: %v", p.Errors())
+	}
+
+	if len(prog.Statements) != 1{
+		t.Fatalf("the len of the statements is wrong, got=%d", len(prog.Statements))
+	}
+
+	st, ok := prog.Statements[0].(*ast.ExpressionStatement)
+	if !ok{
+		t.Fatalf("the program statement is of wrong type, got=%T", prog.Statements[0])
+	}
+
+	exp, ok := st.Expression.(*ast.IfExpression)
+	if !ok{
+		t.Fatalf("expected the if expression, got=%T", st.Expression)
+	}
+
+	if !testInfix(t, exp.Condition, "x", "<", "y"){
+		return
+	}
+
+	branches := []struct{
+		operator string
+		value string
+	}{
+		{">", "y"},
+		{"==", "z"},
+	}
+
+	if len(exp.ElseIfs) != len(branches){
+		t.Fatalf("wrong number of else if branches, want=%d, got=%d", len(branches), len(exp.ElseIfs))
+	}
+
+	for i, b := range branches{
+		branch := exp.ElseIfs[i]
+		if !testInfix(t, branch.Condition, "x", b.operator, "y"){
+			return
+		}
+
+		if len(branch.Consequence.Statements) != 1{
+			t.Fatalf("the else if branch %d has wrong number of statements, got=%d", i, len(branch.Consequence.Statements))
+		}
+
+		con, ok := branch.Consequence.Statements[0].(*ast.ExpressionStatement)
+		if !ok{
+			t.Fatalf("the else if branch %d doesnt have the expected type got=%T", i, branch.Consequence.Statements[0])
+		}
+
+		if !testIdentifier(t, con.Expression, b.value){
+			return
+		}
+	}
+
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1{
+		t.Fatalf("expected the final else block with one statement")
+	}
+
+	alt, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok{
+		t.Fatalf("the alternative doesnt have the expected type got=%T", exp.Alternative.Statements[0])
+	}
+
+	testIdentifier(t, alt.Expression, "w")
+}
+
+// printing a program and parsing the output again should give back the same program
+func TestStringRoundTrip(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"if(x<y){x}", "if((x<y)){x}"},
+		{"if(x<y){x}else{y}", "if((x<y)){x}else{y}"},
+		{"if(x<y){x}else if(x>y){y}", "if((x<y)){x}else if((x>y)){y}"},
+		{"if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }", "if(a){1}else if(b){2}else if(c){3}else{4}"},
+		{"let f = fn(a){ if(a){1}else if(!a){2} };", "let f = fn(a){if(a){1}else if((!a)){2}};"},
+		{"if(a){ if(b){1}else{2} }else if(c){3}", "if(a){if(b){1}else{2}}else if(c){3}"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		prog := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		printed := prog.String()
+		if printed != tt.expected{
+			t.Errorf("wrong string for %q, want=%q, got=%q", tt.input, tt.expected, printed)
+			continue
+		}
+
+		p = New(lexer.New(printed))
+		reparsed := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", printed, p.Errors())
+		}
+
+		if reparsed.String() != printed{
+			t.Errorf("the string did not round trip, want=%q, got=%q", printed, reparsed.String())
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T){
+	input := `match (v) {
+	0 => "zero",
+	-1 => "minus one"
+	[first, _, ...rest] if len(rest) > 0 => first
+	{"type": "user", "id": id} => id
+	_ => null_value
+}`
+
+	p := New(lexer.New(input))
+	prog := p.ParserProgram()
+	if len(p.Errors()) != 0{
+		t.Fatalf("Parser has errors: %v", p.Errors())
+	}
+
+	if len(prog.Statements) != 1{
+		t.Fatalf("the len of the statements is wrong, got=%d", len(prog.Statements))
+	}
+
+	st, ok := prog.Statements[0].(*ast.ExpressionStatement)
+	if !ok{
+		t.Fatalf("the program statement is of wrong type, got=%T", prog.Statements[0])
+	}
+
+	exp, ok := st.Expression.(*ast.MatchExpression)
+	if !ok{
+		t.Fatalf("expected the match expression, got=%T", st.Expression)
+	}
+
+	if !testIdentifier(t, exp.Subject, "v"){
+		return
+	}
+
+	expectedArms := []string{
+		"0 => zero",
+		"(-1) => minus one",
+		"[first,_,...rest] if (len(rest)>0) => first",
+		"{type:user,id:id} => id",
+		"_ => null_value",
+	}
+
+	if len(exp.Arms) != len(expectedArms){
+		t.Fatalf("wrong number of arms, want=%d, got=%d", len(expectedArms), len(exp.Arms))
+	}
+
+	for i, expected := range expectedArms{
+		if exp.Arms[i].String() != expected{
+			t.Errorf("arm %d wrong, want=%q, got=%q", i, expected, exp.Arms[i].String())
+		}
+	}
+
+	array, ok := exp.Arms[2].Pattern.(*ast.ArrayPattern)
+	if !ok{
+		t.Fatalf("expected an array pattern, got=%T", exp.Arms[2].Pattern)
+	}
+
+	if len(array.Elements) != 2{
+		t.Fatalf("wrong number of array pattern elements, got=%d", len(array.Elements))
+	}
+
+	if _, ok := array.Elements[1].(*ast.WildcardPattern); !ok{
+		t.Errorf("expected a wildcard, got=%T", array.Elements[1])
+	}
+
+	if rest, ok := array.Rest.(*ast.BindingPattern); !ok || rest.Name.Value != "rest"{
+		t.Errorf("expected the rest to bind rest, got=%s", array.Rest)
+	}
+
+	if exp.Arms[2].Guard == nil{
+		t.Errorf("expected a guard on the array arm")
+	}
+}
+
+func TestMatchErrors(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"match (x) {}", "match expression has no arms"},
+		{"match (x) { [a, ...r, b] => 1 }", "the rest of an array pattern has to be its last element"},
+		{"match (x) { a + 1 => 1 }", "expected the next token to be =>, got +"},
+		{"match (x) { {a: 1} => 1 }", "expected a literal key in a hash pattern, got var"},
+		{"match (x) { fn => 1 }", "expected a pattern, got fn"},
+		{"match (x) { 1 => 1 2 => 2 }", "expected the next token to be }, got int"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		p.ParserProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0{
+			t.Fatalf("expected an error for %q", tt.input)
+		}
+
+		if diagnostics[0].Message != tt.expected{
+			t.Errorf("wrong error for %q, want=%q, got=%q", tt.input, tt.expected, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T){
+	input := `while(x<y){x; break;}`
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParserProgram()
+
+	if len(p.Errors()) != 0{
+		t.Fatalf("Parser has errors: %v", p.Errors())
+	}
+
+	if len(prog.Statements) != 1{
+		t.Fatalf("the number of statements not as expected, got=%d", len(prog.Statements))
+	}
+
+	st, ok := prog.Statements[0].(*ast.WhileStatement)
+	if !ok{
+		t.Fatalf("the statement is not a while statement, got=%T", prog.Statements[0])
+	}
+
+	if !testInfix(t, st.Condition, "x", "<", "y"){
+		return
+	}
+
+	if len(st.Body.Statements) != 2{
+		t.Fatalf("the number of statements in the body not as expected=2, got=%d", len(st.Body.Statements))
+	}
+
+	if _, ok := st.Body.Statements[1].(*ast.BreakStatement); !ok{
+		t.Errorf("the last statement is not a break statement, got=%T", st.Body.Statements[1])
+	}
+}
+
+func TestForStatement(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"for(let i = 0; i < 10; i){ continue; }", "for(let i = 0;(i<10);i)continue;"},
+		{"for(;;){ break; }", "for(;;)break;"},
+		{"for(i; i; i){}", "for(i;i;i)"},
+		{"for(;x > 1;){ x }", "for(;(x>1);)x"},
+	}
+
+	for _, tt := range tests{
+		l := lexer.New(tt.input)
+		p := New(l)
+		prog := p.ParserProgram()
+
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		if len(prog.Statements) != 1{
+			t.Fatalf("the number of statements not as expected, got=%d", len(prog.Statements))
+		}
+
+		if _, ok := prog.Statements[0].(*ast.ForStatement); !ok{
+			t.Fatalf("the statement is not a for statement, got=%T", prog.Statements[0])
+		}
+
+		if prog.String() != tt.expected{
+			t.Errorf("expected=%q, got=%q", tt.expected, prog.String())
+		}
+	}
+}
+
+func TestAssignExpression(t *testing.T){
+	tests := []struct{
+		input string
+		name string
+		operator string
+		value interface{}
+	}{
+		{"x = 5;", "x", "=", 5},
+		{"y += 1;", "y", "+=", 1},
+		{"z -= a;", "z", "-=", "a"},
+		{"w *= 2;", "w", "*=", 2},
+		{"v /= true;", "v", "/=", true},
+	}
+
+	for _, tt := range tests{
+		l := lexer.New(tt.input)
+		p := New(l)
+		prog := p.ParserProgram()
+
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors: %v", p.Errors())
+		}
+
+		if len(prog.Statements) != 1{
+			t.Fatalf("the number of statements not as expected, got=%d", len(prog.Statements))
+		}
+
+		st, ok := prog.Statements[0].(*ast.ExpressionStatement)
+		if !ok{
+			t.Fatalf("the statement is not an expression statement, got=%T", prog.Statements[0])
+		}
+
+		exp, ok := st.Expression.(*ast.AssignExpression)
+		if !ok{
+			t.Fatalf("the expression is not an assignment, got=%T", st.Expression)
+		}
+
+		if !testIdentifier(t, exp.Name, tt.name){
+			return
+		}
+
+		if exp.Operator != tt.operator{
+			t.Errorf("the operator is not as expected=%s, got=%s", tt.operator, exp.Operator)
+		}
+
+		testLiteralExpression(t, exp.Value, tt.value)
+	}
+}
+
+func TestDestructuringLet(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"let [a, b, ...rest] = arr;", "let [a,b,...rest] = arr;"},
+		{`let {"name": n, "age": a} = person;`, "let {name:n,age:a} = person;"},
+		{"let [a, b = 2, _] = arr;", "let [a,b=2,_] = arr;"},
+		{`let [[a, b], {"k": v = 1 + 2}] = pair;`, "let [[a,b],{k:v=(1+2)}] = pair;"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		prog := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		if len(prog.Statements) != 1{
+			t.Fatalf("the number of statements not as expected, got=%d", len(prog.Statements))
+		}
+
+		let, ok := prog.Statements[0].(*ast.LetStatement)
+		if !ok{
+			t.Fatalf("the statement is not a let statement, got=%T", prog.Statements[0])
+		}
+
+		if let.Pattern == nil || let.Variable != nil{
+			t.Fatalf("expected a destructuring let, got=%s", let.String())
+		}
+
+		if let.String() != tt.expected{
+			t.Errorf("wrong string, want=%q, got=%q", tt.expected, let.String())
+		}
+	}
+}
+
+func TestParallelAssignment(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"a, b = b, a;", "a, b = b, a;"},
+		{"a[0], b = 1, a[1] + 2;", "(a[0]), b = 1, ((a[1])+2);"},
+		{"x, y, z = 1, 2, 3\nx", "x, y, z = 1, 2, 3;x"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		prog := p.ParserProgram()
+		if len(p.Errors()) != 0{
+			t.Fatalf("Parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		if _, ok := prog.Statements[0].(*ast.ParallelAssignStatement); !ok{
+			t.Fatalf("the statement is not a parallel assignment, got=%T", prog.Statements[0])
+		}
+
+		if prog.String() != tt.expected{
+			t.Errorf("wrong string, want=%q, got=%q", tt.expected, prog.String())
+		}
+	}
+}
+
+func TestDestructuringErrors(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{"let [a, 1] = arr;", "cannot destructure into a literal"},
+		{"a, b = 1;", "assignment has 2 targets but 1 values"},
+		{"a, 1 = 1, 2;", "cannot assign to 1"},
+		{"a, b += 1, 2;", "expected the next token to be =, got +="},
+		{"let [a, ...r, b] = arr;", "the rest of an array pattern has to be its last element"},
+		{"`r` + ] ,", "no prefix parse function for ] found"},
+		{"a, b * ] = 1", "no prefix parse function for ] found"},
+	}
+
+	for _, tt := range tests{
+		p := New(lexer.New(tt.input))
+		p.ParserProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0{
+			t.Fatalf("expected an error for %q", tt.input)
+		}
+
+		if diagnostics[0].Message != tt.expected{
+			t.Errorf("wrong error for %q, want=%q, got=%q", tt.input, tt.expected, diagnostics[0].Message)
+		}
+	}
+}
+
+func TestIndexAssignExpression(t *testing.T){
+	input := "arr[1+1] = 5"
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParserProgram()
+
+	if len(p.Errors()) != 0{
+		t.Fatalf("Parser has errors: %v", p.Errors())
+	}
+
+	st, ok := prog.Statements[0].(*ast.ExpressionStatement)
+	if !ok{
+		t.Fatalf("the statement is not an expression statement, got=%T", prog.Statements[0])
+	}
+
+	exp, ok := st.Expression.(*ast.IndexAssignExpression)
+	if !ok{
+		t.Fatalf("the expression is not an index assignment, got=%T", st.Expression)
+	}
+
+	if !testIdentifier(t, exp.Target.Left, "arr"){
+		return
+	}
+
+	if !testInfix(t, exp.Target.Index, 1, "+", 1){
+		return
+	}
+
+	if exp.Operator != "="{
+		t.Errorf("the operator is not as expected==, got=%s", exp.Operator)
+	}
+
+	testLiteralExpression(t, exp.Value, 5)
+}
+
+func TestInvalidAssignTarget(t *testing.T){
+	// the last two have a left side that failed to parse
+	tests := []string{"1 = 2", "f() = 3", "(a+b) += 1", "a * ] = 1", "x = 1; y + ; = 2"}
+
+	for _, input := range tests{
+		l := lexer.New(input)
+		p := New(l)
+		p.ParserProgram()
+
+		if len(p.Errors()) == 0{
+			t.Errorf("expected a parser error for %q", input)
+		}
+	}
+}
+
+func TestIntegerLiteralBases(t *testing.T){
+	tests := []struct{
+		input string
+		expected int64
+	}{
+		{"0xFF;", 255},
+		{"0o17;", 15},
+		{"0b1010;", 10},
+		{"1_000_000;", 1000000},
+		{"0x_ff_ff;", 65535},
+	}
+
+	for _, tt := range tests{
+		l := lexer.New(tt.input)
+		p := New(l)
+		prog := p.ParserProgram()
+
+		if len(p.Errors()) != 0{
+			t.Fatalf("parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		st, ok := prog.Statements[0].(*ast.ExpressionStatement)
+		if !ok{
+			t.Fatalf("the statement is not type is not as expected, got=%T", prog.Statements[0])
+		}
+
+		literal, ok := st.Expression.(*ast.IntegerLiteral)
+		if !ok{
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", st.Expression)
+		}
+
+		if literal.Value != tt.expected{
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+
+	for _, input := range []string{"0x1G;", "1__0;", "1_;", "0b102;"}{
+		l := lexer.New(input)
+		p := New(l)
+		p.ParserProgram()
+
+		if len(p.Errors()) == 0{
+			t.Errorf("expected a parser error for %q", input)
+		}
+	}
+}
+
+func TestFloatLiteral(t *testing.T){
+	tests := []struct{
+		input string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"2.5E+3;", 2500},
+	}
+
+	for _, tt := range tests{
+		l := lexer.New(tt.input)
+		p := New(l)
+		prog := p.ParserProgram()
+
+		if len(p.Errors()) != 0{
+			t.Fatalf("parser has errors for %q: %v", tt.input, p.Errors())
+		}
+
+		st, ok := prog.Statements[0].(*ast.ExpressionStatement)
+		if !ok{
+			t.Fatalf("the statement is not type is not as expected, got=%T", prog.Statements[0])
+		}
+
+		literal, ok := st.Expression.(*ast.FloatLiteral)
+		if !ok{
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", st.Expression)
+		}
+
+		if literal.Value != tt.expected{
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T){
+	tests := []struct{
+		input string
+		expected string
+	}{
+		{`let s = "no end;`, "input:1:9: error: unterminated string\nlet s = \"no end;\n        ^^^^^^^^"},
+		{`puts("a\q")`, "input:1:6: error: invalid escape sequence \\q\nputs(\"a\\q\")\n     ^^^^^"},
+	}
+
+	for _, tt := range tests{
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParserProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0{
+			t.Fatalf("expected a parser error for %q", tt.input)
+		}
+
+		if errors[0].Error() != tt.expected{
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T){
+	tests := []struct{
+		input string
+		expectedMessages []string
+		expectedStatements int
+	}{
+		// one error per broken statement, the statements around it still parse
+		{"let = 5; let y = 2; y", []string{"expected the next token to be var, got ="}, 2},
+		{"let x 5; let y = {1: 2,, 3}; let z = 3;", []string{"expected the next token to be =, got int", "no prefix parse function for , found"}, 2},
+		{"fn(){ let 1; let a = 2; }; let b = (1 + 2", []string{"expected the next token to be var, got int", "expected the next token to be ), got eof"}, 2},
+		{"let f = fn(1){ 1 }; f", []string{"expected the next token to be var, got int"}, 2},
+		{"while(true){ 1 +; } let x = 1;", []string{"no prefix parse function for ; found"}, 2},
+	}
+
+	for _, tt := range tests{
+		l := lexer.New(tt.input)
+		p := New(l)
+		prog := p.ParserProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) != len(tt.expectedMessages){
+			t.Errorf("wrong number of diagnostics for %q. expected=%d, got=%d: %v", tt.input, len(tt.expectedMessages), len(diagnostics), p.Errors())
+			continue
+		}
+
+		for i, message := range tt.expectedMessages{
+			if diagnostics[i].Message != message{
+				t.Errorf("wrong diagnostic %d for %q. expected=%q, got=%q", i, tt.input, message, diagnostics[i].Message)
+			}
+			if diagnostics[i].Severity != SeverityError{
+				t.Errorf("diagnostic %d for %q is not an error, got=%s", i, tt.input, diagnostics[i].Severity)
+			}
+		}
+
+		if len(prog.Statements) != tt.expectedStatements{
+			t.Errorf("wrong number of statements for %q. expected=%d, got=%d", tt.input, tt.expectedStatements, len(prog.Statements))
+		}
+	}
+}
+
+func TestParserStopsAtEOF(t *testing.T){
+	tests := []string{"return x", "return", "let a = fn(){ return 1", "{1: 2,", "if(true){ return", "for(let i = 0"}
+
+	for _, input := range tests{
+		l := lexer.New(input)
+		p := New(l)
+		p.ParserProgram()
+	}
+
+	l := lexer.New("fn(){ return }")
+	p := New(l)
+	prog := p.ParserProgram()
+	if len(p.Errors()) != 0{
+		t.Fatalf("parser has errors for a bare return: %v", p.Errors())
+	}
+
+	fn := prog.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionExpression)
+	ret, ok := fn.Body.Statements[0].(*ast.ReturnStatement)
+	if !ok || ret.Value != nil{
+		t.Fatalf("expected a return statement without a value, got=%s", fn.Body.String())
+	}
+}
+
+func TestDiagnosticFormat(t *testing.T){
+	l := lexer.NewWithFile("main.dl", "let total = 1;\nlet 2 = total;")
+	p := New(l)
+	p.ParserProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1{
+		t.Fatalf("expected one diagnostic, got=%d: %v", len(diagnostics), p.Errors())
+	}
+
+	expected := "main.dl:2:5: error: expected the next token to be var, got int\nlet 2 = total;\n    ^\nhint: give the variable a name, like let x = 1;"
+	if diagnostics[0].Error() != expected{
+		t.Errorf("wrong diagnostic. expected=%q, got=%q", expected, diagnostics[0].Error())
+	}
+}
+
+func TestOptionalSemicolons(t *testing.T){
+	withSemicolons := `let add = fn(a, b) { return a + b; };
+let h = {"one": {"two": 2}};
+let r = if (add(1, 2) > 2) { h["one"]; } else { [1, 2]; };
+while (r < 10) { r += 1; if (r == 5) { break; } };
+return r;`
+
+	withoutSemicolons := `let add = fn(a, b) {
+	return a + b
+}
+let h = {
+	"one": {
+		"two": 2
+	}
+}
+let r = if (add(1,
+	2) > 2) {
+	h["one"]
+}
+else {
+	[
+		1,
+		2
+	]
+}
+while (r < 10)
+{
+	r += 1
+	if (r == 5) { break }
+}
+return r`
+
+	programs := []string{}
+	for _, input := range []string{withSemicolons, withoutSemicolons}{
+		l := lexer.New(input)
+		p := New(l)
+		prog := p.ParserProgram()
+
+		if len(p.Errors()) != 0{
+			t.Fatalf("parser has errors: %v", p.Errors())
+		}
+
+		programs = append(programs, prog.String())
+	}
+
+	if programs[0] != programs[1]{
+		t.Errorf("programs parse differently.\nwith=%s\nwithout=%s", programs[0], programs[1])
+	}
+}
+
+func TestNodeSpans(t *testing.T){
+	input := "let total = add(1,\n  2 * 3);"
+
+	l := lexer.New(input)
+	p := New(l)
+	prog := p.ParserProgram()
+
+	if len(p.Errors()) != 0{
+		t.Fatalf("parser has errors: %v", p.Errors())
+	}
+
+	let := prog.Statements[0].(*ast.LetStatement)
+	call := let.Value.(*ast.CallExpression)
+	product := call.Arguments[1].(*ast.InfixExpression)
+
+	tests := []struct{
+		node ast.ASTNode
+		expected string
+	}{
+		{prog, "1:1-2:10"},
+		{let, "1:1-2:10"},
+		{let.Variable, "1:5-1:10"},
+		{call, "1:13-2:9"},
+		{call.Function, "1:13-1:16"},
+		{call.Arguments[0], "1:17-1:18"},
+		{product, "2:3-2:8"},
+		{product.Right, "2:7-2:8"},
+	}
+
+	for i, tt := range tests{
+		span := tt.node.GetSpan()
+		got := span.Start.String() + "-" + span.End.String()
+		if got != tt.expected{
+			t.Errorf("tests[%d] - wrong span for %s. expected=%s, got=%s", i, tt.node.String(), tt.expected, got)
+		}
+	}
+}
+
 func TestCallExpression(t *testing.T){
 	input := `add(1, 2*3, 4+5)`
 
@@ -355,6 +1421,7 @@ func TestPrefixExpression(t *testing.T){
 		{"-10;","-",10},
 		{"!true;","!",true},
 		{"!false;","!",false},
+		{"~7;","~",7},
 	}
 
 	for _, tt := range tests{
@@ -402,9 +1469,20 @@ func TestInfixExpression(t *testing.T){
 		{"5<5;", 5, "<", 5},
 		{"5==5;", 5, "==", 5},
 		{"5!=5;", 5, "!=", 5},
+		{"5<=5;", 5, "<=", 5},
+		{"5>=5;", 5, ">=", 5},
+		{"5%5;", 5, "%", 5},
+		{"5**5;", 5, "**", 5},
+		{"5&5;", 5, "&", 5},
+		{"5|5;", 5, "|", 5},
+		{"5^5;", 5, "^", 5},
+		{"5<<5;", 5, "<<", 5},
+		{"5>>5;", 5, ">>", 5},
 		{"true==true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"true && false", true, "&&", false},
+		{"a || b", "a", "||", "b"},
 	}
 
 	for _, tt := range tests{
@@ -468,6 +1546,31 @@ func TestOperatorPrecendence(t *testing.T){
 		{"a + add(b*c) +d", "((a+add((b*c)))+d)"},
 		{"add(a,b,1,2*3,4+5,add(6,7*8))","add(a,b,1,(2*3),(4+5),add(6,(7*8)))"},
 		{"a * [1,2,3,4][b*c]*d","((a*([1,2,3,4][(b*c)]))*d)"},
+		{"a = b = c","(a=(b=c))"},
+		{"a += 1 + 2 * 3","(a+=(1+(2*3)))"},
+		{"a -= b == c","(a-=(b==c))"},
+		{"a *= f(x = 1)","(a*=f((x=1)))"},
+		{"a[i] = b[j] += 1","((a[i])=((b[j])+=1))"},
+		{"a || b && c","(a||(b&&c))"},
+		{"a && b || c","((a&&b)||c)"},
+		{"a && b && c","((a&&b)&&c)"},
+		{"a == b && c < d","((a==b)&&(c<d))"},
+		{"!a || -b","((!a)||(-b))"},
+		{"x = a || b","(x=(a||b))"},
+		{"5>=4 == 3<=4","((5>=4)==(3<=4))"},
+		{"a+1 <= b*2","((a+1)<=(b*2))"},
+		{"a*b%c","((a*b)%c)"},
+		{"a+b%c","(a+(b%c))"},
+		{"2**3**2","(2**(3**2))"},
+		{"-2**2","(-(2**2))"},
+		{"a*b**c","(a*(b**c))"},
+		{"2**-1","(2**(-1))"},
+		{"a|b^c&d","(a|(b^(c&d)))"},
+		{"a&b == c","((a&b)==c)"},
+		{"a<<1+b","(a<<(1+b))"},
+		{"a>>b < c<<d","((a>>b)<(c<<d))"},
+		{"~a&b","((~a)&b)"},
+		{"a|b && c","((a|b)&&c)"},
 	}
 
 	for _,tt := range tests{
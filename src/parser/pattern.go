@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"github.com/singlaanish56/Compiler-in-go/ast"
+	"github.com/singlaanish56/Compiler-in-go/token"
+)
+
+// parses the pattern starting at the current token. a destructuring let
+// takes defaults for names but can't test against literals like a match arm
+func (p *Parser) parsePattern(destructuring bool) ast.Pattern{
+	start := p.currToken
+
+	var pattern ast.Pattern
+	switch p.currToken.Type{
+	case token.UNDERSCORE:
+		pattern = &ast.WildcardPattern{Token: p.currToken}
+	case token.VARIABLE:
+		binding := &ast.BindingPattern{Token: p.currToken, Name: &ast.Variable{Token: p.currToken, Value: p.currToken.Identifier, Span: ast.TokenSpan(p.currToken)}}
+		if destructuring && p.peekTokenIs(token.EQUALTO){
+			p.nextToken()
+			p.nextToken()
+			binding.Default = p.parseExpression(LOWEST)
+			if binding.Default == nil{
+				return nil
+			}
+		}
+		pattern = binding
+	case token.NUMBER, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+		if destructuring{
+			p.addError(ast.TokenSpan(p.currToken), "cannot destructure into a literal").Hint = "literal patterns can only be used in a match arm"
+			return nil
+		}
+
+		value := p.parseLiteralPatternValue()
+		if value == nil{
+			return nil
+		}
+		pattern = &ast.LiteralPattern{Token: start, Value: value}
+	case token.OPENBRACKET:
+		pattern = p.parseArrayPattern(destructuring)
+	case token.OPENBRACE:
+		pattern = p.parseHashPattern(destructuring)
+	default:
+		p.addError(ast.TokenSpan(p.currToken), "expected a pattern, got %s", p.currToken.Type)
+		return nil
+	}
+
+	if pattern == nil{
+		return nil
+	}
+
+	pattern.SetSpan(p.spanFrom(start))
+	return pattern
+}
+
+// a literal, or a minus directly in front of a number
+func (p *Parser) parseLiteralPatternValue() ast.Expression{
+	start := p.currToken
+	if !p.currTokenIs(token.MINUS){
+		value := p.prefixParserMap[p.currToken.Type]()
+		if value != nil{
+			value.SetSpan(ast.TokenSpan(start))
+		}
+		return value
+	}
+
+	if !p.peekTokenIs(token.NUMBER) && !p.peekTokenIs(token.FLOAT){
+		p.addError(ast.TokenSpan(p.peekToken), "expected a number after - in a pattern, got %s", p.peekToken.Type)
+		return nil
+	}
+
+	p.nextToken()
+	right := p.prefixParserMap[p.currToken.Type]()
+	if right == nil{
+		return nil
+	}
+	right.SetSpan(ast.TokenSpan(p.currToken))
+
+	value := &ast.PrefixExpression{Token: start, Operator: start.Identifier, Right: right}
+	value.SetSpan(p.spanFrom(start))
+	return value
+}
+
+// [first, _, ...rest], the rest has to come last
+func (p *Parser) parseArrayPattern(destructuring bool) ast.Pattern{
+	pattern := &ast.ArrayPattern{Token: p.currToken}
+
+	for !p.peekTokenIs(token.CLOSEBRACKET){
+		p.nextToken()
+
+		if p.currTokenIs(token.ELLIPSIS){
+			if !p.peekTokenIs(token.VARIABLE) && !p.peekTokenIs(token.UNDERSCORE){
+				p.addError(ast.TokenSpan(p.peekToken), "expected a name or _ after ..., got %s", p.peekToken.Type)
+				return nil
+			}
+
+			p.nextToken()
+			pattern.Rest = p.parsePattern(false)
+			if !p.peekTokenIs(token.CLOSEBRACKET){
+				p.addError(ast.TokenSpan(p.peekToken), "the rest of an array pattern has to be its last element").Hint = "move the ... element to the end"
+				return nil
+			}
+			break
+		}
+
+		element := p.parsePattern(destructuring)
+		if element == nil{
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.CLOSEBRACKET) && !p.checkPeek(token.COMMA){
+			return nil
+		}
+	}
+
+	if !p.checkPeek(token.CLOSEBRACKET){
+		return nil
+	}
+
+	return pattern
+}
+
+// {"key": pattern, ...}, the keys are string, integer or boolean literals
+func (p *Parser) parseHashPattern(destructuring bool) ast.Pattern{
+	pattern := &ast.HashPattern{Token: p.currToken}
+
+	for !p.peekTokenIs(token.CLOSEBRACE){
+		p.nextToken()
+
+		switch p.currToken.Type{
+		case token.STRING, token.NUMBER, token.TRUE, token.FALSE:
+		default:
+			p.addError(ast.TokenSpan(p.currToken), "expected a literal key in a hash pattern, got %s", p.currToken.Type)
+			return nil
+		}
+
+		key := p.prefixParserMap[p.currToken.Type]()
+		if key == nil{
+			return nil
+		}
+		key.SetSpan(ast.TokenSpan(p.currToken))
+
+		if !p.checkPeek(token.COLON){
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parsePattern(destructuring)
+		if value == nil{
+			return nil
+		}
+
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.peekTokenIs(token.CLOSEBRACE) && !p.checkPeek(token.COMMA){
+			return nil
+		}
+	}
+
+	if !p.checkPeek(token.CLOSEBRACE){
+		return nil
+	}
+
+	return pattern
+}
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/singlaanish56/Compiler-in-go/ast"
@@ -18,7 +17,7 @@ func (p *Parser) parseNumber() ast.Expression{
 
 	val, err := strconv.ParseInt(p.currToken.Identifier, 0 , 64)
 	if err != nil{
-		p.errors = append(p.errors, fmt.Errorf("could not parse %s as integer", p.currToken.Identifier))
+		p.addError(ast.TokenSpan(p.currToken), "could not parse %s as integer", p.currToken.Identifier)
 		return nil
 	}
 
@@ -26,6 +25,25 @@ func (p *Parser) parseNumber() ast.Expression{
 	return integerLiteral
 }
 
+func (p *Parser) parseFloat() ast.Expression{
+	floatLiteral := &ast.FloatLiteral{Token: p.currToken}
+
+	val, err := strconv.ParseFloat(p.currToken.Identifier, 64)
+	if err != nil{
+		p.addError(ast.TokenSpan(p.currToken), "could not parse %s as float", p.currToken.Identifier)
+		return nil
+	}
+
+	floatLiteral.Value = val
+	return floatLiteral
+}
+
+// the lexer already described what is wrong with the token
+func (p *Parser) parseLexerError() ast.Expression{
+	p.addError(ast.TokenSpan(p.currToken), "%s", p.currToken.Identifier)
+	return nil
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression{
 	prefixExpression := &ast.PrefixExpression{Token: p.currToken, Operator: p.currToken.Identifier}
 	p.nextToken()
@@ -39,6 +57,32 @@ func (p *Parser) parseStringExpression() ast.Expression{
 	return strLiteral
 }
 
+// the lexer splits the string at every ${ and its }, so the parts alternate text and expression
+func (p *Parser) parseInterpolatedString() ast.Expression{
+	str := &ast.InterpolatedString{Token: p.currToken}
+	str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value: p.currToken.Identifier})
+
+	for p.currTokenIs(token.STRINGHEAD) || p.currTokenIs(token.STRINGMIDDLE){
+		if p.peekTokenIs(token.STRINGMIDDLE) || p.peekTokenIs(token.STRINGTAIL){
+			p.addError(ast.TokenSpan(p.peekToken), "expected an expression inside ${}")
+			return nil
+		}
+
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+
+		if !p.peekTokenIs(token.STRINGMIDDLE) && !p.peekTokenIs(token.STRINGTAIL){
+			p.peekError(token.CLOSEBRACE).Hint = "check for a missing } after the expression in ${"
+			return nil
+		}
+
+		p.nextToken()
+		str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value: p.currToken.Identifier})
+	}
+
+	return str
+}
+
 func (p *Parser) parseBooleanExpression() ast.Expression{
 	return &ast.BooleanLiteral{Token: p.currToken, Value: p.currTokenIs(token.TRUE)}
 }
@@ -51,6 +95,18 @@ func (p *Parser) parseArrayExpression() ast.Expression{
 	return arr
 }
 
+func (p *Parser) parseSpreadExpression() ast.Expression{
+	exp := &ast.SpreadExpression{Token: p.currToken}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	if exp.Value == nil{
+		return nil
+	}
+
+	return exp
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression{
 	p.nextToken()
 
@@ -114,61 +170,202 @@ func (p *Parser) parseIfExpression() ast.Expression{
 	}
 	
 	exp.Consequence = p.parseBlockStatement()
-	if p.peekTokenIs(token.ELSE){
+	for p.peekTokenIs(token.ELSE){
 		p.nextToken()
 
+		if p.peekTokenIs(token.IF){
+			p.nextToken()
+			branch := p.parseElseIfBranch()
+			if branch == nil{
+				return nil
+			}
+
+			exp.ElseIfs = append(exp.ElseIfs, branch)
+			continue
+		}
+
 		if !p.checkPeek(token.OPENBRACE){
 			return nil
 		}
 
 		exp.Alternative = p.parseBlockStatement()
+		break
 	}
 
 	return exp
 }
 
-func (p *Parser) parseFunctionExpression() ast.Expression{
-	exp := &ast.FunctionExpression{Token: p.currToken}
+// match (subject) { pattern => value, pattern if guard => value }, arms are
+// separated by commas or line ends and tried in order
+func (p *Parser) parseMatchExpression() ast.Expression{
+	exp := &ast.MatchExpression{Token: p.currToken}
 
 	if !p.checkPeek(token.OPENROUND){
 		return nil
 	}
 
-	exp.Parameters = p.parseFunctionArguments()
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.checkPeek(token.CLOSEROUND){
+		return nil
+	}
 
 	if !p.checkPeek(token.OPENBRACE){
 		return nil
 	}
 
-	exp.Body = p.parseBlockStatement()
+	for !p.peekTokenIs(token.CLOSEBRACE){
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil{
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON){
+			p.nextToken()
+		}else if !p.peekTokenIs(token.CLOSEBRACE){
+			p.peekError(token.CLOSEBRACE)
+			return nil
+		}
+	}
+
+	if !p.checkPeek(token.CLOSEBRACE){
+		return nil
+	}
+
+	if len(exp.Arms) == 0{
+		p.addError(p.spanFrom(exp.Token), "match expression has no arms")
+		return nil
+	}
 
 	return exp
 }
 
-func (p *Parser) parseFunctionArguments() []*ast.Variable{
-	params := []*ast.Variable{}
+func (p *Parser) parseMatchArm() *ast.MatchArm{
+	arm := &ast.MatchArm{Token: p.currToken}
 
-	if p.peekTokenIs(token.CLOSEROUND){
+	arm.Pattern = p.parsePattern(false)
+	if arm.Pattern == nil{
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF){
+		p.nextToken()
 		p.nextToken()
-		return params
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.checkPeek(token.ARROW){
+		return nil
 	}
 
 	p.nextToken()
+	arm.Body = p.parseExpression(LOWEST)
+	if arm.Body == nil{
+		return nil
+	}
 
-	arg := &ast.Variable{Token: p.currToken, Value: p.currToken.Identifier}
-	params = append(params, arg)
+	arm.SetSpan(p.spanFrom(arm.Token))
+	return arm
+}
 
-	for p.peekTokenIs(token.COMMA){
-		p.nextToken()
-		p.nextToken()
+func (p *Parser) parseElseIfBranch() *ast.ElseIfBranch{
+	branch := &ast.ElseIfBranch{Token: p.currToken}
 
-		arg = &ast.Variable{Token: p.currToken, Value: p.currToken.Identifier}
-		params = append(params, arg)
+	if !p.checkPeek(token.OPENROUND){
+		return nil
 	}
 
+	p.nextToken()
+
+	branch.Condition = p.parseExpression(LOWEST)
+
 	if !p.checkPeek(token.CLOSEROUND){
 		return nil
 	}
 
-	return params
+	if !p.checkPeek(token.OPENBRACE){
+		return nil
+	}
+
+	branch.Consequence = p.parseBlockStatement()
+	branch.SetSpan(p.spanFrom(branch.Token))
+
+	return branch
+}
+
+func (p *Parser) parseFunctionExpression() ast.Expression{
+	exp := &ast.FunctionExpression{Token: p.currToken}
+
+	if !p.checkPeek(token.OPENROUND){
+		return nil
+	}
+
+	if !p.parseFunctionArguments(exp){
+		return nil
+	}
+
+	if !p.checkPeek(token.OPENBRACE){
+		return nil
+	}
+
+	exp.Body = p.parseBlockStatement()
+
+	return exp
+}
+
+// plain parameters come first, then the ones with a default and last the
+// rest parameter
+func (p *Parser) parseFunctionArguments(fn *ast.FunctionExpression) bool{
+	fn.Parameters = []*ast.Variable{}
+	fn.Defaults = []ast.Expression{}
+
+	for !p.peekTokenIs(token.CLOSEROUND){
+		if len(fn.Parameters) > 0 || fn.Rest != nil{
+			if !p.checkPeek(token.COMMA){
+				return false
+			}
+		}
+
+		if p.peekTokenIs(token.ELLIPSIS){
+			p.nextToken()
+			if !p.checkPeek(token.VARIABLE){
+				return false
+			}
+
+			fn.Rest = &ast.Variable{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value: p.currToken.Identifier}
+			if !p.peekTokenIs(token.CLOSEROUND){
+				p.addError(ast.TokenSpan(p.peekToken), "the rest parameter has to be the last one").Hint = "move ..." + fn.Rest.Value + " to the end"
+				return false
+			}
+			break
+		}
+
+		if !p.checkPeek(token.VARIABLE){
+			return false
+		}
+
+		arg := &ast.Variable{Token: p.currToken, Span: ast.TokenSpan(p.currToken), Value: p.currToken.Identifier}
+
+		var defaultValue ast.Expression
+		if p.peekTokenIs(token.EQUALTO){
+			p.nextToken()
+			p.nextToken()
+			defaultValue = p.parseExpression(LOWEST)
+			if defaultValue == nil{
+				return false
+			}
+		}else if len(fn.Defaults) > 0 && fn.Defaults[len(fn.Defaults)-1] != nil{
+			p.addError(arg.Span, "parameter %s needs a default, it follows one that has a default", arg.Value)
+			return false
+		}
+
+		fn.Parameters = append(fn.Parameters, arg)
+		fn.Defaults = append(fn.Defaults, defaultValue)
+	}
+
+	return p.checkPeek(token.CLOSEROUND)
 }
\ No newline at end of file
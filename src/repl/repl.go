@@ -2,8 +2,11 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 
 	"github.com/singlaanish56/Compiler-in-go/compiler"
 	"github.com/singlaanish56/Compiler-in-go/lexer"
@@ -22,6 +25,7 @@ func Start(in io.Reader, out io.Writer) {
 	for i, v := range object.Builtins {
 		symbolTable.DefineBuiltin(i, v.Name)
 	}
+	modules := compiler.NewModules(searchPath(".")...)
 
 	for {
 		fmt.Fprintf(out, PROMPT)
@@ -31,7 +35,7 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
-		l := lexer.New(line)
+		l := lexer.NewWithFile("repl", line)
 		p := parser.New(l)
 
 		program := p.ParserProgram()
@@ -40,29 +44,68 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
+		// a line that fails keeps none of its names or imports, a module
+		// it imported never ran and is compiled again by the next import
+		symbols, loaded := symbolTable.Save(), modules.Save()
+
 		compiler := compiler.NewWithState(symbolTable, constants)
+		compiler.UseModules(modules)
 		err := compiler.Compile(program)
 		if err != nil {
+			symbolTable.Restore(symbols)
+			modules.Restore(loaded)
 			fmt.Fprintf(out, "Woops, Compiler failed:\n %s\n", err)
 			continue
 		}
 
 		bytecode := compiler.Bytecode()
-		constants = bytecode.Constants
 
 		vmMachine := vm.NewWithGlobalStore(bytecode, globalStore)
 		err = vmMachine.Run()
 		if err != nil {
+			symbolTable.Restore(symbols)
+			modules.Restore(loaded)
 			fmt.Fprintf(out, "Woops, VM failed:\n %s\n", err)
 			continue
 		}
 
+		constants = bytecode.Constants
+
 		stackTop := vmMachine.LastPoppedStackElement()
 		io.WriteString(out, stackTop.Inspect())
 		io.WriteString(out, "\n")
 	}
 }
 
+// compiles and runs a whole file, its imports are looked up next to it
+// and then in the directories listed in DEMAPATH
+func RunFile(path string) error {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	p := parser.New(lexer.NewWithFile(path, string(input)))
+	program := p.ParserProgram()
+	if len(p.Errors()) != 0 {
+		return errors.Join(p.Errors()...)
+	}
+
+	c := compiler.New()
+	c.UseModules(compiler.NewModules(searchPath(filepath.Dir(path))...))
+	err = c.Compile(program)
+	if err != nil {
+		return err
+	}
+
+	return vm.New(c.Bytecode()).Run()
+}
+
+// dir first, then the directories listed in DEMAPATH
+func searchPath(dir string) []string {
+	return append([]string{dir}, filepath.SplitList(os.Getenv("DEMAPATH"))...)
+}
+
 func printParserErrors(out io.Writer, parserErrors []error) {
 	io.WriteString(out, "ran into these parser errors:\n")
 	for _, err := range parserErrors {
@@ -0,0 +1,40 @@
+package repl
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFailedLinesAreForgotten(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("..", "testdata", "modules"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DEMAPATH", dir)
+
+	input := strings.Join([]string{
+		`import "counter.dm" as c; nope`,
+		`import "counter.dm" as c; c.n`,
+		`let x = 5; x()`,
+		`x`,
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := []string{
+		"undefined variable nope",
+		PROMPT + "1\n",
+		"calling a non function",
+		"undefined variable x",
+	}
+
+	output := out.String()
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected the output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
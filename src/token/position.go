@@ -0,0 +1,84 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lines and columns start at 1, columns count runes
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// the text being compiled, kept so errors can quote the line they point at
+type Source struct {
+	Name  string
+	lines []string
+}
+
+func NewSource(name, text string) *Source {
+	return &Source{Name: name, lines: strings.Split(text, "\n")}
+}
+
+// the source line, without its newline, empty when out of range
+func (s *Source) Line(line int) string {
+	if line < 1 || line > len(s.lines) {
+		return ""
+	}
+
+	return strings.TrimSuffix(s.lines[line-1], "\r")
+}
+
+// file:line:col: message, then the line itself with carets under the range
+func (s *Source) Format(start, end Position, message string) string {
+	if s == nil {
+		return fmt.Sprintf("%s: %s", start, message)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s:%s: %s", s.Name, start, message)
+
+	line := []rune(s.Line(start.Line))
+	if len(line) == 0 {
+		return out.String()
+	}
+
+	// a range running onto later lines is underlined to the end of its first line
+	width := end.Column - start.Column
+	if end.Line != start.Line {
+		width = len(line) - start.Column + 1
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	var indent strings.Builder
+	for i := 0; i < start.Column-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+
+	fmt.Fprintf(&out, "\n%s\n%s%s", string(line), indent.String(), strings.Repeat("^", width))
+	return out.String()
+}
+
+// an error pointing at a range of the source
+type SourceError struct {
+	Source  *Source
+	Start   Position
+	End     Position
+	Message string
+}
+
+func (e *SourceError) Error() string {
+	return e.Source.Format(e.Start, e.End, e.Message)
+}
@@ -7,6 +7,21 @@ type Token struct{
 	Identifier string
 	StartPosition int
 	EndPosition int
+	Line int
+	Column int
+	EndLine int
+	EndColumn int
+	// comments between the previous token and this one, kept for tools that want to preserve them
+	Comments []string
+}
+
+func (t Token) Pos() Position{
+	return Position{Offset: t.StartPosition, Line: t.Line, Column: t.Column}
+}
+
+// the position just past the token
+func (t Token) EndPos() Position{
+	return Position{Offset: t.EndPosition, Line: t.EndLine, Column: t.EndColumn}
 }
 
 var KeywordMap = map[string]TokenType{
@@ -19,6 +34,14 @@ var KeywordMap = map[string]TokenType{
 	"return":RETURN,
 	"null":NULL,
 	"var":VARIABLE,
+	"while":WHILE,
+	"for":FOR,
+	"break":BREAK,
+	"continue":CONTINUE,
+	"match":MATCH,
+	"import":IMPORT,
+	"export":EXPORT,
+	"as":AS,
 }
 
 
@@ -28,10 +51,23 @@ const(
 	IF="if"
 	ELSE="else"
 	RETURN="return"
+	WHILE="while"
+	FOR="for"
+	BREAK="break"
+	CONTINUE="continue"
+	MATCH="match"
+	IMPORT="import"
+	EXPORT="export"
+	AS="as"
 
 	VARIABLE="var"
 	STRING="str"
+	// the parts of a string with ${} in it, "a ${x} b ${y} c" is a head, a middle and a tail
+	STRINGHEAD="str${"
+	STRINGMIDDLE="}str${"
+	STRINGTAIL="}str"
 	NUMBER="int"
+	FLOAT="float"
 	TRUE="t"
 	FALSE="f"
 	NULL="nullptr"
@@ -52,12 +88,32 @@ const(
 	MINUS="-"
 	DIVIDE="/"
 	MULTIPLY="*"
+	MODULO="%"
+	POWER="**"
+	AMPERSAND="&"
+	PIPE="|"
+	CARET="^"
+	TILDE="~"
+	LEFTSHIFT="<<"
+	RIGHTSHIFT=">>"
 	EQUALTO="="
+	ARROW="=>"
+	ELLIPSIS="..."
+	DOT="."
 	UNDERSCORE="_"
 	DOUBLEEQUALTO="=="
 	EXCLAMATION="!"
 	EXCLAMATIONEQUALTO="!="
+	OPENANGLEEQUALTO="<="
+	CLOSEANGLEEQUALTO=">="
+	PLUSEQUALTO="+="
+	MINUSEQUALTO="-="
+	MULTIPLYEQUALTO="*="
+	DIVIDEEQUALTO="/="
+	AND="&&"
+	OR="||"
 
 	INVALID="inv"
+	ERROR="err" // a malformed token, the identifier holds the message
 	EOF="eof"
 )
\ No newline at end of file
@@ -0,0 +1,180 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/singlaanish56/Compiler-in-go/code"
+	"github.com/singlaanish56/Compiler-in-go/object"
+)
+
+// what the vm does when an integer operation does not fit in an int64
+type OverflowPolicy int
+
+const (
+	OverflowWrap  OverflowPolicy = iota // two's complement wrap around, the default
+	OverflowError                       // stop with a runtime error
+	OverflowBig                         // promote the result to an arbitrary precision BigInteger
+)
+
+var operatorSymbols = map[code.Opcode]string{
+	code.OpAdd:       "+",
+	code.OpSub:       "-",
+	code.OpMul:       "*",
+	code.OpDiv:       "/",
+	code.OpPow:       "**",
+	code.OpShiftLeft: "<<",
+}
+
+func (vm *VM) SetOverflowPolicy(policy OverflowPolicy) {
+	vm.overflowPolicy = policy
+}
+
+// called with the wrapped result once an integer operation has overflowed
+func (vm *VM) handleIntegerOverflow(op code.Opcode, left, right *object.Integer, wrapped int64) error {
+	switch vm.overflowPolicy {
+	case OverflowError:
+		return fmt.Errorf("integer overflow: %d %s %d", left.Value, operatorSymbols[op], right.Value)
+	case OverflowBig:
+		return vm.executeBigIntegerBinaryOperation(op, left, right)
+	default:
+		return vm.push(&object.Integer{Value: wrapped})
+	}
+}
+
+func (vm *VM) handleNegationOverflow(right *object.Integer) error {
+	switch vm.overflowPolicy {
+	case OverflowError:
+		return fmt.Errorf("integer overflow: -(%d)", right.Value)
+	case OverflowBig:
+		return vm.push(normalizeBigInteger(new(big.Int).Neg(toBigInt(right))))
+	default:
+		return vm.push(&object.Integer{Value: -right.Value})
+	}
+}
+
+// at least one operand is a BigInteger, or an int64 operation overflowed under the big policy
+func (vm *VM) executeBigIntegerBinaryOperation(op code.Opcode, left, right object.Object) error {
+	leftVal := toBigInt(left)
+	rightVal := toBigInt(right)
+	result := new(big.Int)
+
+	switch op {
+	case code.OpAdd:
+		result.Add(leftVal, rightVal)
+	case code.OpSub:
+		result.Sub(leftVal, rightVal)
+	case code.OpMul:
+		result.Mul(leftVal, rightVal)
+	case code.OpDiv:
+		if rightVal.Sign() == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		result.Quo(leftVal, rightVal)
+	case code.OpMod:
+		if rightVal.Sign() == 0 {
+			return fmt.Errorf("modulo by zero")
+		}
+		result.Rem(leftVal, rightVal)
+	case code.OpPow:
+		if rightVal.Sign() < 0 {
+			return fmt.Errorf("negative exponent for integer power: %s", rightVal)
+		}
+		result.Exp(leftVal, rightVal, nil)
+	case code.OpBitAnd:
+		result.And(leftVal, rightVal)
+	case code.OpBitOr:
+		result.Or(leftVal, rightVal)
+	case code.OpBitXor:
+		result.Xor(leftVal, rightVal)
+	case code.OpShiftLeft, code.OpShiftRight:
+		if rightVal.Sign() < 0 {
+			return fmt.Errorf("negative shift count: %s", rightVal)
+		}
+		if !rightVal.IsInt64() || rightVal.Int64() > math.MaxInt32 {
+			return fmt.Errorf("shift count too large: %s", rightVal)
+		}
+		if op == code.OpShiftLeft {
+			result.Lsh(leftVal, uint(rightVal.Int64()))
+		} else {
+			result.Rsh(leftVal, uint(rightVal.Int64()))
+		}
+	default:
+		return fmt.Errorf("unsupported binary operation %d", op)
+	}
+
+	return vm.push(normalizeBigInteger(result))
+}
+
+func (vm *VM) executeBigIntegerComparison(op code.Opcode, left, right object.Object) error {
+	cmp := toBigInt(left).Cmp(toBigInt(right))
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(toBooleanObject(cmp == 0))
+	case code.OpNotEqual:
+		return vm.push(toBooleanObject(cmp != 0))
+	case code.OpGreaterThan:
+		return vm.push(toBooleanObject(cmp > 0))
+	case code.OpLessThan:
+		return vm.push(toBooleanObject(cmp < 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(toBooleanObject(cmp >= 0))
+	case code.OpLessThanOrEqual:
+		return vm.push(toBooleanObject(cmp <= 0))
+	default:
+		return fmt.Errorf("unsupported comparison operation %d", op)
+	}
+}
+
+// results that fit back into an int64 become plain integers again
+func normalizeBigInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+
+	return &object.BigInteger{Value: value}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInteger:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIG_INTEGER_OBJ
+}
+
+func addOverflows(a, b, result int64) bool {
+	return (a > 0 && b > 0 && result < 0) || (a < 0 && b < 0 && result >= 0)
+}
+
+func subOverflows(a, b, result int64) bool {
+	return (b < 0 && result < a) || (b > 0 && result > a)
+}
+
+func mulOverflows(a, b, result int64) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+
+	return result/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64)
+}
+
+func shiftLeftOverflows(a, count, result int64) bool {
+	if a == 0 {
+		return false
+	}
+	if count >= 64 {
+		return true
+	}
+
+	return result>>count != a
+}
@@ -2,6 +2,8 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 
 	"github.com/singlaanish56/Compiler-in-go/code"
 	"github.com/singlaanish56/Compiler-in-go/compiler"
@@ -17,17 +19,17 @@ var False = &object.Boolean{Value: false}
 var Null = &object.Null{}
 
 type Frame struct {
-	fn           *object.CompiledFunction
+	cl           *object.Closure
 	ip           int
 	framePointer int
 }
 
-func NewFrame(fn *object.CompiledFunction, framePointer int) *Frame {
-	return &Frame{fn, -1, framePointer}
+func NewFrame(cl *object.Closure, framePointer int) *Frame {
+	return &Frame{cl, -1, framePointer}
 }
 
 func (f *Frame) Instructions() code.Instructions {
-	return f.fn.Instructions
+	return f.cl.Fn.Instructions
 }
 
 type VM struct {
@@ -40,11 +42,14 @@ type VM struct {
 	stackPointer int
 
 	globalStore []object.Object
+
+	overflowPolicy OverflowPolicy
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
 	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
-	mainFrame := NewFrame(mainFn, 0)
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
 	frames := make([]*Frame, MaxFrames)
 	frames[0] = mainFrame
 
@@ -97,7 +102,8 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
@@ -110,7 +116,7 @@ func (vm *VM) Run() error {
 			if err := vm.push(False); err != nil {
 				return err
 			}
-		case code.OpGreaterThan, code.OpLessThan, code.OpEqual, code.OpNotEqual:
+		case code.OpGreaterThan, code.OpLessThan, code.OpGreaterThanOrEqual, code.OpLessThanOrEqual, code.OpEqual, code.OpNotEqual:
 			if err := vm.executeComparison(op); err != nil {
 				return err
 			}
@@ -122,6 +128,10 @@ func (vm *VM) Run() error {
 			if err := vm.executeMinusOperation(); err != nil {
 				return err
 			}
+		case code.OpBitNot:
+			if err := vm.executeBitNotOperation(); err != nil {
+				return err
+			}
 		case code.OpJumpNotTruthy:
 			pos := int(code.ReadUint16(ins[i+1:]))
 			vm.currentFrame().ip += 2
@@ -130,6 +140,16 @@ func (vm *VM) Run() error {
 			if !isTruthy(condition) {
 				vm.currentFrame().ip = pos - 1
 			}
+		case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+			pos := int(code.ReadUint16(ins[i+1:]))
+			vm.currentFrame().ip += 2
+
+			jumpWhen := op == code.OpJumpTruthyOrPop
+			if isTruthy(vm.StackTop()) == jumpWhen {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
 		case code.OpJump:
 			pos := int(code.ReadUint16(ins[i+1:]))
 			vm.currentFrame().ip = pos - 1
@@ -196,6 +216,37 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpSlice:
+			end := vm.pop()
+			start := vm.pop()
+			objectToBeSliced := vm.pop()
+
+			err := vm.executeSliceExpression(objectToBeSliced, start, end)
+			if err != nil {
+				return err
+			}
+		case code.OpToString:
+			err := vm.executeToString(vm.pop())
+			if err != nil {
+				return err
+			}
+		case code.OpIndexKeep:
+			index := vm.stack[vm.stackPointer-1]
+			objectToBeIndexed := vm.stack[vm.stackPointer-2]
+
+			err := vm.executeIndexExpression(objectToBeIndexed, index)
+			if err != nil {
+				return err
+			}
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			objectToBeIndexed := vm.pop()
+
+			err := vm.executeSetIndex(objectToBeIndexed, index, value)
+			if err != nil {
+				return err
+			}
 		case code.OpCall:
 			numArgs := code.ReadUint8(ins[i+1:])
 			vm.currentFrame().ip += 1
@@ -231,6 +282,112 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[i+1:])
+			numFree := code.ReadUint8(ins[i+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[i+1:])
+			vm.currentFrame().ip += 1
+
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+		case code.OpCurrentClosure:
+			currentClosure := vm.currentFrame().cl
+			err := vm.push(currentClosure)
+			if err != nil {
+				return err
+			}
+		case code.OpPatchFree:
+			freeIndex := code.ReadUint8(ins[i+1:])
+			vm.currentFrame().ip += 1
+
+			value := vm.pop()
+			target := vm.pop()
+			closure, ok := target.(*object.Closure)
+			if !ok {
+				return fmt.Errorf("not a closure: %+v", target)
+			}
+			closure.Free[freeIndex] = value
+		case code.OpMatchArray:
+			length := int(code.ReadUint16(ins[i+1:]))
+			hasRest := code.ReadUint8(ins[i+3:]) == 1
+			vm.currentFrame().ip += 3
+
+			err := vm.push(toBooleanObject(matchesArray(vm.pop(), length, hasRest)))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchHash:
+			_, ok := vm.pop().(*object.Hash)
+			err := vm.push(toBooleanObject(ok))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchKey:
+			key := vm.pop()
+			hash := vm.pop()
+
+			err := vm.executeMatchKey(hash, key)
+			if err != nil {
+				return err
+			}
+		case code.OpArrayRest:
+			start := int(code.ReadUint16(ins[i+1:]))
+			vm.currentFrame().ip += 2
+
+			value := vm.pop()
+			array, ok := value.(*object.Array)
+			if !ok {
+				return fmt.Errorf("rest element not supported: %s", value.Type())
+			}
+
+			rest := []object.Object{}
+			if start < len(array.Elements) {
+				rest = make([]object.Object, len(array.Elements)-start)
+				copy(rest, array.Elements[start:])
+			}
+			err := vm.push(&object.Array{Elements: rest})
+			if err != nil {
+				return err
+			}
+		case code.OpArrayExtend:
+			value := vm.pop()
+			array := vm.pop()
+
+			err := vm.executeArrayExtend(array, value)
+			if err != nil {
+				return err
+			}
+		case code.OpCallSpread:
+			value := vm.pop()
+			args, ok := value.(*object.Array)
+			if !ok {
+				return fmt.Errorf("cannot spread %s", value.Type())
+			}
+
+			for _, arg := range args.Elements {
+				err := vm.push(arg)
+				if err != nil {
+					return err
+				}
+			}
+
+			err := vm.executeCall(len(args.Elements))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchFail:
+			value := vm.pop()
+			return fmt.Errorf("no match arm for value %s", value.Inspect())
 		case code.OpPop:
 			vm.pop()
 		}
@@ -259,9 +416,14 @@ func (vm *VM) currentFrame() *Frame {
 	return vm.frames[vm.framesIndex-1]
 }
 
-func (vm *VM) pushFrame(frame *Frame) {
+func (vm *VM) pushFrame(frame *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("stack overflow")
+	}
+
 	vm.frames[vm.framesIndex] = frame
 	vm.framesIndex++
+	return nil
 }
 
 func (vm *VM) popFrame() *Frame {
@@ -278,6 +440,10 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 
 	if leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ {
 		return vm.executeIntegerBinaryOperation(op, left, right)
+	} else if isInteger(left) && isInteger(right) {
+		return vm.executeBigIntegerBinaryOperation(op, left, right)
+	} else if isNumber(left) && isNumber(right) {
+		return vm.executeFloatBinaryOperation(op, left, right)
 	} else if leftType == object.STRING_OBJ && rightType == object.STRING_OBJ {
 		return vm.executeStringBinaryOperation(op, left, right)
 	}
@@ -285,27 +451,90 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	return fmt.Errorf("unsupported types for binary operation: %s %s", leftType, rightType)
 }
 
+// overflow is detected here and handed to the vm's overflow policy
 func (vm *VM) executeIntegerBinaryOperation(op code.Opcode, left, right object.Object) error {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 	var result int64
+	overflowed := false
 	switch op {
 	case code.OpAdd:
 		result = leftVal + rightVal
+		overflowed = addOverflows(leftVal, rightVal, result)
 	case code.OpSub:
 		result = leftVal - rightVal
+		overflowed = subOverflows(leftVal, rightVal, result)
 	case code.OpMul:
 		result = leftVal * rightVal
+		overflowed = mulOverflows(leftVal, rightVal, result)
 	case code.OpDiv:
+		if rightVal == 0 {
+			return fmt.Errorf("division by zero")
+		}
 		result = leftVal / rightVal
-
+		overflowed = leftVal == math.MinInt64 && rightVal == -1
+	case code.OpMod:
+		if rightVal == 0 {
+			return fmt.Errorf("modulo by zero")
+		}
+		result = leftVal % rightVal
+	case code.OpPow:
+		if rightVal < 0 {
+			return fmt.Errorf("negative exponent for integer power: %d", rightVal)
+		}
+		result, overflowed = integerPower(leftVal, rightVal)
+	case code.OpBitAnd:
+		result = leftVal & rightVal
+	case code.OpBitOr:
+		result = leftVal | rightVal
+	case code.OpBitXor:
+		result = leftVal ^ rightVal
+	case code.OpShiftLeft, code.OpShiftRight:
+		if rightVal < 0 {
+			return fmt.Errorf("negative shift count: %d", rightVal)
+		}
+		if op == code.OpShiftLeft {
+			result = leftVal << rightVal
+			overflowed = shiftLeftOverflows(leftVal, rightVal, result)
+		} else {
+			result = leftVal >> rightVal
+		}
 	default:
 		return fmt.Errorf("unsupported binary operation %d", op)
 	}
 
+	if overflowed {
+		return vm.handleIntegerOverflow(op, left.(*object.Integer), right.(*object.Integer), result)
+	}
+
 	return vm.push(&object.Integer{Value: result})
 }
 
+// used when at least one operand is a float, the integer one is converted
+func (vm *VM) executeFloatBinaryOperation(op code.Opcode, left, right object.Object) error {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+	var result float64
+	switch op {
+	case code.OpAdd:
+		result = leftVal + rightVal
+	case code.OpSub:
+		result = leftVal - rightVal
+	case code.OpMul:
+		result = leftVal * rightVal
+	case code.OpDiv:
+		result = leftVal / rightVal
+	case code.OpMod:
+		result = math.Mod(leftVal, rightVal)
+	case code.OpPow:
+		result = math.Pow(leftVal, rightVal)
+	default:
+		return fmt.Errorf("unsupported types for binary operation: %s %s", left.Type(), right.Type())
+	}
+
+	return vm.push(&object.Float{Value: result})
+}
+
 func (vm *VM) executeStringBinaryOperation(operation code.Opcode, left, right object.Object) error {
 	if operation != code.OpAdd {
 		return fmt.Errorf("unkown string operation, %d", operation)
@@ -314,24 +543,30 @@ func (vm *VM) executeStringBinaryOperation(operation code.Opcode, left, right ob
 	leftValue := left.(*object.String).Value
 	rightValue := right.(*object.String).Value
 
-	return vm.push(&object.String{leftValue + rightValue})
+	return vm.push(&object.String{Value: leftValue + rightValue})
 }
 
 func (vm *VM) executeComparison(op code.Opcode) error {
 	right := vm.pop()
 	left := vm.pop()
 
-	if left.Type() == object.INTEGER_OBJ || right.Type() == object.INTEGER_OBJ {
+	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
 		return vm.executeIntegerComparison(op, left, right)
+	} else if isInteger(left) && isInteger(right) {
+		return vm.executeBigIntegerComparison(op, left, right)
+	} else if isNumber(left) && isNumber(right) {
+		return vm.executeFloatComparison(op, left, right)
+	} else if left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ {
+		return vm.executeStringComparison(op, left, right)
 	}
 
 	switch op {
 	case code.OpEqual:
-		return vm.push(toBooleanObject(right == left))
+		return vm.push(toBooleanObject(object.Equal(left, right)))
 	case code.OpNotEqual:
-		return vm.push(toBooleanObject(right != left))
+		return vm.push(toBooleanObject(!object.Equal(left, right)))
 	default:
-		return fmt.Errorf("unsupported comparison operation %d", op)
+		return fmt.Errorf("unsupported types for comparison: %s %s", left.Type(), right.Type())
 	}
 }
 
@@ -348,35 +583,116 @@ func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object
 		return vm.push(toBooleanObject(leftVal > rightVal))
 	case code.OpLessThan:
 		return vm.push(toBooleanObject(leftVal < rightVal))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(toBooleanObject(leftVal >= rightVal))
+	case code.OpLessThanOrEqual:
+		return vm.push(toBooleanObject(leftVal <= rightVal))
 	default:
 		return fmt.Errorf("unsupported comparison operation %d", op)
 	}
 }
 
-func (vm *VM) executeBangOperation() error {
-	right := vm.pop()
+func (vm *VM) executeFloatComparison(op code.Opcode, left, right object.Object) error {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(toBooleanObject(leftVal == rightVal))
+	case code.OpNotEqual:
+		return vm.push(toBooleanObject(leftVal != rightVal))
+	case code.OpGreaterThan:
+		return vm.push(toBooleanObject(leftVal > rightVal))
+	case code.OpLessThan:
+		return vm.push(toBooleanObject(leftVal < rightVal))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(toBooleanObject(leftVal >= rightVal))
+	case code.OpLessThanOrEqual:
+		return vm.push(toBooleanObject(leftVal <= rightVal))
+	default:
+		return fmt.Errorf("unsupported comparison operation %d", op)
+	}
+}
 
-	switch right {
-	case True:
-		return vm.push(False)
-	case False:
-		return vm.push(True)
-	case Null:
-		return vm.push(True)
+// strings are ordered lexicographically by their bytes
+func (vm *VM) executeStringComparison(op code.Opcode, left, right object.Object) error {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(toBooleanObject(leftVal == rightVal))
+	case code.OpNotEqual:
+		return vm.push(toBooleanObject(leftVal != rightVal))
+	case code.OpGreaterThan:
+		return vm.push(toBooleanObject(leftVal > rightVal))
+	case code.OpLessThan:
+		return vm.push(toBooleanObject(leftVal < rightVal))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(toBooleanObject(leftVal >= rightVal))
+	case code.OpLessThanOrEqual:
+		return vm.push(toBooleanObject(leftVal <= rightVal))
 	default:
-		return fmt.Errorf("unsupported bang operation %s", right.Type())
+		return fmt.Errorf("unsupported comparison operation %d", op)
 	}
 }
 
+func (vm *VM) executeBangOperation() error {
+	right := vm.pop()
+
+	return vm.push(toBooleanObject(!isTruthy(right)))
+}
+
 func (vm *VM) executeMinusOperation() error {
 	right := vm.pop()
 
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return vm.handleNegationOverflow(right)
+		}
+		return vm.push(&object.Integer{Value: -right.Value})
+	case *object.BigInteger:
+		return vm.push(normalizeBigInteger(new(big.Int).Neg(right.Value)))
+	case *object.Float:
+		return vm.push(&object.Float{Value: -right.Value})
+	default:
 		return fmt.Errorf("unsupported type for minus operation %s", right.Type())
 	}
+}
 
-	rightVal := right.(*object.Integer).Value
-	return vm.push(&object.Integer{Value: -rightVal})
+func (vm *VM) executeBitNotOperation() error {
+	right := vm.pop()
+
+	switch right := right.(type) {
+	case *object.Integer:
+		return vm.push(&object.Integer{Value: ^right.Value})
+	case *object.BigInteger:
+		return vm.push(normalizeBigInteger(new(big.Int).Not(right.Value)))
+	default:
+		return fmt.Errorf("unsupported type for bitwise not operation %s", right.Type())
+	}
+}
+
+// exponentiation by squaring, returns the wrapped result and whether it overflowed
+func integerPower(base, exponent int64) (int64, bool) {
+	result := int64(1)
+	overflowed := false
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			product := result * base
+			overflowed = overflowed || mulOverflows(result, base, product)
+			result = product
+		}
+		exponent >>= 1
+		if exponent > 0 {
+			square := base * base
+			overflowed = overflowed || mulOverflows(base, base, square)
+			base = square
+		}
+	}
+
+	return result, overflowed
 }
 
 func (vm *VM) buildArray(startIndex, endIndex int) *object.Array {
@@ -412,6 +728,8 @@ func (vm *VM) executeIndexExpression(objectToBeIndexed, index object.Object) err
 	switch {
 	case objectToBeIndexed.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return vm.executeArrayIndex(objectToBeIndexed, index)
+	case objectToBeIndexed.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeStringIndex(objectToBeIndexed, index)
 	case objectToBeIndexed.Type() == object.HASHPAIR_OBJ:
 		return vm.executeHashIndex(objectToBeIndexed, index)
 	default:
@@ -430,15 +748,101 @@ func (vm *VM) executeArrayIndex(array, index object.Object) error {
 		return fmt.Errorf("object is not an integer, got=%T", index)
 	}
 
-	i := indexObject.Value
-	maxIndex := int64(len(arrayObject.Elements))
-	if i < 0 || i >= maxIndex {
+	i, ok := elementIndex(indexObject.Value, int64(len(arrayObject.Elements)))
+	if !ok {
 		return vm.push(Null)
 	}
 
 	return vm.push(arrayObject.Elements[i])
 }
 
+// strings index by character, not by byte
+func (vm *VM) executeStringIndex(str, index object.Object) error {
+	characters := []rune(str.(*object.String).Value)
+
+	i, ok := elementIndex(index.(*object.Integer).Value, int64(len(characters)))
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(&object.String{Value: string(characters[i])})
+}
+
+// a negative index counts from the end like a slice bound, -1 is the last
+// element. false when the index is out of range either way
+func elementIndex(i, length int64) (int64, bool) {
+	if i < 0 {
+		i += length
+	}
+
+	return i, i >= 0 && i < length
+}
+
+// strings go into an interpolated string as they are, anything else as it prints
+func (vm *VM) executeToString(value object.Object) error {
+	if str, ok := value.(*object.String); ok {
+		return vm.push(str)
+	}
+
+	return vm.push(&object.String{Value: value.Inspect()})
+}
+
+// a negative bound counts from the end and both are clamped to the length,
+// the result is a new array or string
+func (vm *VM) executeSliceExpression(objectToBeSliced, start, end object.Object) error {
+	var length int64
+	switch value := objectToBeSliced.(type) {
+	case *object.Array:
+		length = int64(len(value.Elements))
+	case *object.String:
+		length = int64(len([]rune(value.Value)))
+	default:
+		return fmt.Errorf("slice operator not supported: %s", objectToBeSliced.Type())
+	}
+
+	from, err := sliceBound(start, 0, length)
+	if err != nil {
+		return err
+	}
+
+	to, err := sliceBound(end, length, length)
+	if err != nil {
+		return err
+	}
+
+	if to < from {
+		to = from
+	}
+
+	switch value := objectToBeSliced.(type) {
+	case *object.Array:
+		elements := make([]object.Object, to-from)
+		copy(elements, value.Elements[from:to])
+		return vm.push(&object.Array{Elements: elements})
+	default:
+		characters := []rune(value.(*object.String).Value)
+		return vm.push(&object.String{Value: string(characters[from:to])})
+	}
+}
+
+func sliceBound(bound object.Object, missing, length int64) (int64, error) {
+	if bound == Null {
+		return missing, nil
+	}
+
+	integer, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, fmt.Errorf("slice index must be an integer, got %s", bound.Type())
+	}
+
+	i := integer.Value
+	if i < 0 {
+		i += length
+	}
+
+	return max(0, min(i, length)), nil
+}
+
 func (vm *VM) executeHashIndex(hash, index object.Object) error {
 	hashObject, ok := hash.(*object.Hash)
 	if !ok {
@@ -458,32 +862,166 @@ func (vm *VM) executeHashIndex(hash, index object.Object) error {
 	return vm.push(pair.Value)
 }
 
+// writes into the collection itself, every binding that refers to the same
+// array or hash sees the change
+func (vm *VM) executeSetIndex(objectToBeIndexed, index, value object.Object) error {
+	switch collection := objectToBeIndexed.(type) {
+	case *object.Array:
+		indexObject, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("array index must be an integer, got %s", index.Type())
+		}
+
+		length := int64(len(collection.Elements))
+		i, ok := elementIndex(indexObject.Value, length)
+		if !ok {
+			return fmt.Errorf("index out of range: %d for array of length %d", indexObject.Value, length)
+		}
+
+		collection.Elements[i] = value
+	case *object.Hash:
+		hashKey, ok := index.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("unhashable type %s", index.Type())
+		}
+
+		collection.Pairs[hashKey.HashKey()] = object.HashPair{Key: index, Value: value}
+	default:
+		return fmt.Errorf("index assignment not supported: %s", objectToBeIndexed.Type())
+	}
+
+	return vm.push(value)
+}
+
+func matchesArray(value object.Object, length int, hasRest bool) bool {
+	array, ok := value.(*object.Array)
+	if !ok {
+		return false
+	}
+
+	if hasRest {
+		return len(array.Elements) >= length
+	}
+
+	return len(array.Elements) == length
+}
+
+func (vm *VM) executeMatchKey(hash, key object.Object) error {
+	hashObject, ok := hash.(*object.Hash)
+	if !ok {
+		return fmt.Errorf("object is not a hash, got=%T", hash)
+	}
+
+	hashKey, ok := key.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("unhashable type %s", key.Type())
+	}
+
+	_, ok = hashObject.Pairs[hashKey.HashKey()]
+	return vm.push(toBooleanObject(ok))
+}
+
 func (vm *VM) executeCall(numArgs int) error {
 	callee := vm.stack[vm.stackPointer-1-numArgs]
 	switch callee := callee.(type) {
-	case *object.CompiledFunction:
-		return vm.callFunction(callee, numArgs)
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
 	case *object.Builtin:
 		return vm.callBuiltin(callee, numArgs)
 	default:
 		return fmt.Errorf("calling a non function or a non builtin")
 	}
+}
+
+// missing arguments that have a default are passed as null, the function's
+// own code then replaces them. the arguments past the parameters of a
+// variadic function are gathered into the array of its rest parameter
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	err := checkArity(cl.Fn, numArgs)
+	if err != nil {
+		return err
+	}
+
+	framePointer := vm.stackPointer - numArgs
+	// the locals are written to the stack directly, not through push
+	if framePointer+cl.Fn.NumberOfLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	for i := numArgs; i < cl.Fn.NumberOfParameters; i++ {
+		vm.stack[framePointer+i] = Null
+	}
+
+	if cl.Fn.Variadic {
+		restStart := framePointer + cl.Fn.NumberOfParameters
+		rest := []object.Object{}
+		if vm.stackPointer > restStart {
+			rest = vm.buildArray(restStart, vm.stackPointer).Elements
+		}
+		vm.stack[restStart] = &object.Array{Elements: rest}
+	}
+
+	frame := NewFrame(cl, framePointer)
+	err = vm.pushFrame(frame)
+	if err != nil {
+		return err
+	}
+	vm.stackPointer = frame.framePointer + cl.Fn.NumberOfLocals
 
 	return nil
 }
 
-func (vm *VM) callFunction(fn *object.CompiledFunction, numArgs int) error {
-	if numArgs != fn.NumberOfParameters {
+func checkArity(fn *object.CompiledFunction, numArgs int) error {
+	required := fn.NumberOfParameters - fn.NumberOfDefaults
+	switch {
+	case fn.Variadic && numArgs < required:
+		return fmt.Errorf("wrong number of arguments: want at least %d, got=%d", required, numArgs)
+	case fn.Variadic:
+		return nil
+	case fn.NumberOfDefaults == 0 && numArgs != fn.NumberOfParameters:
 		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", fn.NumberOfParameters, numArgs)
+	case numArgs < required || numArgs > fn.NumberOfParameters:
+		return fmt.Errorf("wrong number of arguments: want=%d to %d, got=%d", required, fn.NumberOfParameters, numArgs)
 	}
 
-	frame := NewFrame(fn, vm.stackPointer-numArgs)
-	vm.pushFrame(frame)
-	vm.stackPointer = frame.framePointer + fn.NumberOfLocals
-
 	return nil
 }
 
+func (vm *VM) executeArrayExtend(array, value object.Object) error {
+	arrayObject, ok := array.(*object.Array)
+	if !ok {
+		return fmt.Errorf("object is not an array, got=%T", array)
+	}
+
+	valueObject, ok := value.(*object.Array)
+	if !ok {
+		return fmt.Errorf("cannot spread %s", value.Type())
+	}
+
+	elements := make([]object.Object, 0, len(arrayObject.Elements)+len(valueObject.Elements))
+	elements = append(elements, arrayObject.Elements...)
+	elements = append(elements, valueObject.Elements...)
+
+	return vm.push(&object.Array{Elements: elements})
+}
+
+func (vm *VM) pushClosure(constIndex, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.stackPointer-numFree+i]
+	}
+	vm.stackPointer = vm.stackPointer - numFree
+
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
 func (vm *VM) callBuiltin(fn *object.Builtin, numOfArgs int) error {
 	args := vm.stack[vm.stackPointer-numOfArgs : vm.stackPointer]
 	result := fn.Fn(args...)
@@ -507,14 +1045,24 @@ func toBooleanObject(val bool) *object.Boolean {
 	return False
 }
 
-func isTruthy(obj object.Object) bool {
-	switch obj := obj.(type) {
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
 
-	case *object.Boolean:
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInteger:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.Float:
 		return obj.Value
-	case *object.Null:
-		return false
 	default:
-		return true
+		return 0
 	}
 }
+
+func isTruthy(obj object.Object) bool {
+	return object.IsTruthy(obj)
+}
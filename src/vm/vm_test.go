@@ -2,6 +2,8 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"path/filepath"
 	"testing"
 
 	"github.com/singlaanish56/Compiler-in-go/ast"
@@ -43,6 +45,19 @@ func testBooleanObject(expected bool, obj object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, obj object.Object) error {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not a Float, got=%T(%+v)", obj, obj)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value, got=%g, want=%g", result.Value, expected)
+	}
+
+	return nil
+}
+
 func testStringObject(expected string, obj object.Object) error {
 	result, ok := obj.(*object.String)
 	if !ok {
@@ -63,6 +78,11 @@ type vmTestCase struct {
 
 func runVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
+	runVmTestsWithPolicy(t, tests, OverflowWrap)
+}
+
+func runVmTestsWithPolicy(t *testing.T, tests []vmTestCase, policy OverflowPolicy) {
+	t.Helper()
 
 	for _, tt := range tests {
 		program := parse(tt.input)
@@ -74,6 +94,7 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 		}
 
 		vm := New(compiler.Bytecode())
+		vm.SetOverflowPolicy(policy)
 		err = vm.Run()
 		if err != nil {
 			t.Fatalf("failed to run vm: %s", err)
@@ -86,6 +107,36 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 	}
 }
 
+func runVmErrorTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+	runVmErrorTestsWithPolicy(t, tests, OverflowWrap)
+}
+
+func runVmErrorTestsWithPolicy(t *testing.T, tests []vmTestCase, policy OverflowPolicy) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetOverflowPolicy(policy)
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q but resulted in none", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
 func testExpectedObject(t *testing.T, expected any, obj object.Object) {
 	t.Helper()
 
@@ -95,6 +146,11 @@ func testExpectedObject(t *testing.T, expected any, obj object.Object) {
 		if err != nil {
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
+	case float64:
+		err := testFloatObject(expected, obj)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
 	case bool:
 		err := testBooleanObject(bool(expected), obj)
 		if err != nil {
@@ -121,6 +177,21 @@ func testExpectedObject(t *testing.T, expected any, obj object.Object) {
 				t.Errorf("testIntegerObject failed: %s", err)
 			}
 		}
+	case []any:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			t.Errorf("object is not an Array, got=%T(%+v)", obj, obj)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong number of elements in array, expected=%d, got=%d", len(expected), len(array.Elements))
+			return
+		}
+
+		for i, expectedElem := range expected {
+			testExpectedObject(t, expectedElem, array.Elements[i])
+		}
 	case map[object.HashKey]int64:
 		hash, ok := obj.(*object.Hash)
 		if !ok {
@@ -170,6 +241,29 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"-5", -5},
 		{"-10", -10},
 		{"-50+10", -40},
+		{"7%3", 1},
+		{"-7%3", -1},
+		{"6%3", 0},
+		{"2**10", 1024},
+		{"2**0", 1},
+		{"-2**2", -4},
+		{"(-2)**3", -8},
+		{"2**3**2", 512},
+		{"3*2**2", 12},
+		{"12&10", 8},
+		{"12|10", 14},
+		{"12^10", 6},
+		{"~0", -1},
+		{"~5", -6},
+		{"+5", 5},
+		{"3 - +2", 1},
+		{"1<<4", 16},
+		{"256>>4", 16},
+		{"-16>>2", -4},
+		{"1<<64", 0},
+		{"1+2<<1", 6},
+		{"5&1 == 1", true},
+		{"let x = 10; x % 4 + x ** 2", 102},
 	}
 
 	runVmTests(t, tests)
@@ -189,6 +283,13 @@ func TestBooleanLiterals(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!false", true},
+		{"1<=2", true},
+		{"2<=2", true},
+		{"3<=2", false},
+		{"1>=2", false},
+		{"2>=2", true},
+		{"3>=2", true},
+		{"-1<=-1", true},
 	}
 
 	runVmTests(t, tests)
@@ -207,6 +308,39 @@ func TestConditionals(t *testing.T) {
 		{"if(false){10}", Null},
 		{"!(if(false){10})", true},
 		{"if((if(false){10})){10}else{20}", 20},
+		{"if(true){}", Null},
+		{"if(true){let a = 1;}", Null},
+		{"if(false){10}else{let a = 1;}", Null},
+		{"if(1>2){10}else if(2>1){20}else{30}", 20},
+		{"if(1>2){10}else if(2>3){20}else{30}", 30},
+		{"if(1>2){10}else if(2>3){20}", Null},
+		{"let x = 3; if(x==1){10}else if(x==2){20}else if(x==3){30}else{40}", 30},
+		{"let f = fn(x){ if(x<0){-1}else if(x==0){0}else{1} }; [f(-5), f(0), f(5)]", []int{-1, 0, 1}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTruthiness(t *testing.T) {
+	tests := []vmTestCase{
+		{"!5", false},
+		{"!0", false},
+		{"!2.5", false},
+		{`!"x"`, false},
+		{`!""`, false},
+		{"![]", false},
+		{"!{}", false},
+		{"!fn(){}", false},
+		{"!!5", true},
+		{`!!""`, true},
+		{"!!(if(false){1})", false},
+		{"if(0){10}else{20}", 10},
+		{`if(""){10}else{20}`, 10},
+		{"if([]){10}else{20}", 10},
+		{"if(!0){10}else{20}", 20},
+		{`0 || 5`, 0},
+		{`[] && 5`, 5},
+		{`!0 || "y"`, "y"},
 	}
 
 	runVmTests(t, tests)
@@ -267,7 +401,7 @@ func TestIndexExpressions(t *testing.T) {
 		{"[[1,1,1]][0][0]", 1},
 		{"[][0]", Null},
 		{"[1,2,3][99]", Null},
-		{"[1][-1]", Null},
+		{"[1][-1]", 1},
 		{"{1:1, 2:2}[1]", 1},
 		{"{1:1, 2:2}[2]", 2},
 		{"{}[0]", Null},
@@ -276,6 +410,86 @@ func TestIndexExpressions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestStringIndexing(t *testing.T) {
+	tests := []vmTestCase{
+		{`"abc"[0]`, "a"},
+		{`"abc"[2]`, "c"},
+		{`"abc"[3]`, Null},
+		{`"abc"[-1]`, "c"},
+		{`"héllo"[1]`, "é"},
+		{`let s = "xyz"; s[len(s) - 1]`, "z"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []vmTestCase{
+		{`let name = "ann"; let items = [1, 2]; "user ${name} has ${len(items)} items"`, "user ann has 2 items"},
+		{`"${1 + 2}"`, "3"},
+		{`"${1.5} ${true} ${[1, "a"]}"`, "1.5 true [1, a]"},
+		{`"null is ${if (false) { 1 }}"`, "null is null"},
+		{`"${"a"}${"b"}"`, "ab"},
+		{`"outer ${"inner ${1}"}"`, "outer inner 1"},
+		{`let f = fn(x) { "x=${x}" }; f(5)`, "x=5"},
+		{`"\${x}"`, "${x}"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestNegativeIndexing(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", Null},
+		{"[][-1]", Null},
+		{"let a = [1, 2, 3]; [a[-1], a[-1:]]", []any{3, []int{3}}},
+		{`"abc"[-1]`, "c"},
+		{`"héllo"[-4]`, "é"},
+		{`"abc"[-4]`, Null},
+		{`""[-1]`, Null},
+		{"let a = [1, 2, 3]; a[-1] = 9; a", []int{1, 2, 9}},
+		{"let a = [1, 2, 3]; a[-2] += 10; a", []int{1, 12, 3}},
+		{"let [x] = [[1, 2]]; x[-1]", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][-3:-1]", []int{2, 3}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[1, 2, 3, 4][-10:10]", []int{1, 2, 3, 4}},
+		{"[][0:5]", []int{}},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", []int{1, 2, 3}},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[:-1]`, "hell"},
+		{`"hello"[-3:]`, "llo"},
+		{`"hello"[4:2]`, ""},
+		{`"héllo"[1:3]`, "él"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSliceErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`{"a": 1}[0:1]`, "slice operator not supported: HASHPAIR"},
+		{`[1, 2]["a":]`, "slice index must be an integer, got STRING"},
+		{`"abc"[:true]`, "slice index must be an integer, got BOOLEAN"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
 func TestFunctions(t *testing.T) {
 	tests := []vmTestCase{
 		{`let fivePlusTen = fn(){5+10;}; fivePlusTen();`, 15},
@@ -321,6 +535,18 @@ func TestCallArgumentsWithoutWrongArguments(t *testing.T) {
 			input:    `fn(a,b){a+b;}(1)`,
 			expected: `wrong number of arguments: want=2, got=1`,
 		},
+		{
+			input:    `fn(a, b = 1){a+b;}(1, 2, 3)`,
+			expected: `wrong number of arguments: want=1 to 2, got=3`,
+		},
+		{
+			input:    `fn(a, b = 1){a+b;}()`,
+			expected: `wrong number of arguments: want=1 to 2, got=0`,
+		},
+		{
+			input:    `fn(a, ...r){a;}()`,
+			expected: `wrong number of arguments: want at least 1, got=0`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -388,3 +614,670 @@ func TestBuiltinFunctions(t *testing.T) {
 	runVmTests(t, tests)
 
 }
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{`let newClosure = fn(a){ fn(){ a; }; }; let closure = newClosure(99); closure();`, 99},
+		{`let adder = fn(x){ fn(y){ x + y } }; let addTwo = adder(2); addTwo(3);`, 5},
+		{`let adder = fn(x){ fn(y){ x + y } }; adder(1)(2) + adder(10)(20);`, 33},
+		{`let newAdder = fn(a, b){ let c = a + b; fn(d){ c + d }; }; let adder = newAdder(1, 2); adder(8);`, 11},
+		{`let newAdderOuter = fn(a, b){ let c = a + b; fn(d){ let e = d + c; fn(f){ e + f; }; }; };
+		let newAdderInner = newAdderOuter(1, 2);
+		let adder = newAdderInner(3);
+		adder(8);`, 14},
+		{`let a = 1;
+		let newAdderOuter = fn(b){ fn(c){ fn(d){ a + b + c + d }; }; };
+		let newAdderInner = newAdderOuter(2);
+		let adder = newAdderInner(3);
+		adder(8);`, 14},
+		{`let newClosure = fn(a, b){ let one = fn(){ a; }; let two = fn(){ b; }; fn(){ one() + two(); }; };
+		let closure = newClosure(9, 90);
+		closure();`, 99},
+		{`let apply = fn(f, v){ f(v) }; let scale = fn(k){ apply(fn(n){ n * k }, 7) }; scale(3);`, 21},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`let countDown = fn(x){ if(x == 0){ return 0; } else { countDown(x - 1); } }; countDown(1);`, 0},
+		{`let countDown = fn(x){ if(x == 0){ return 0; } else { countDown(x - 1); } }; let wrapper = fn(){ countDown(1); }; wrapper();`, 0},
+		{`let wrapper = fn(){ let countDown = fn(x){ if(x == 0){ return 0; } else { countDown(x - 1); } }; countDown(1); }; wrapper();`, 0},
+		{`let outer = fn(){ let middle = fn(){ let countDown = fn(x){ if(x == 0){ return 0; } else { countDown(x - 1); } }; countDown(3); }; middle(); }; outer();`, 0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRecursiveFibonacci(t *testing.T) {
+	tests := []vmTestCase{
+		{`let fibonacci = fn(x){ if(x == 0){ return 0; } else { if(x == 1){ return 1; } else { fibonacci(x - 1) + fibonacci(x - 2); } } }; fibonacci(15);`, 610},
+		{`let wrapper = fn(n){
+			let fib = fn(x){ if(x < 2){ return x; } fib(x - 1) + fib(x - 2); };
+			fib(n);
+		};
+		wrapper(15);`, 610},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMutualRecursion(t *testing.T) {
+	tests := []vmTestCase{
+		{`let isEven = fn(n){ if(n == 0){ true } else { isOdd(n - 1) } };
+		let isOdd = fn(n){ if(n == 0){ false } else { isEven(n - 1) } };
+		isEven(10);`, true},
+		{`let wrapper = fn(){
+			let isEven = fn(n){ if(n == 0){ true } else { isOdd(n - 1) } };
+			let isOdd = fn(n){ if(n == 0){ false } else { isEven(n - 1) } };
+			[isEven(10), isOdd(10), isEven(7), isOdd(7)];
+		};
+		wrapper();`, []any{true, false, false, true}},
+		{`let wrapper = fn(){
+			let isEven = fn(n){ if(n == 0){ true } else { isOdd(n - 1) } };
+			let isOdd = fn(n){ if(n == 0){ false } else { isEven(n - 1) } };
+			isOdd(7);
+		};
+		wrapper();`, true},
+		{`let wrapper = fn(){
+			let isEven = fn(n){ if(n == 0){ true } else { isOdd(n - 1) } };
+			let isOdd = fn(n){ if(n == 0){ false } else { isEven(n - 1) } };
+			isEven(7);
+		};
+		wrapper();`, false},
+		{`let outer = fn(limit){
+			let inner = fn(){
+				let ping = fn(n){ if(n == limit){ n } else { pong(n + 1) } };
+				let pong = fn(n){ if(n == limit){ n } else { ping(n + 1) } };
+				ping(0);
+			};
+			inner();
+		};
+		outer(9);`, 9},
+		{`let isEven = fn(n){ if(n == 0){ true } else { isOdd(n - 1) } };
+		let limit = 10;
+		let isOdd = fn(n){ if(n == 0){ false } else { isEven(n - 1) } };
+		isEven(limit);`, true},
+		{`let wrapper = fn(){
+			let isEven = fn(n){ if(n == 0){ true } else { isOdd(n - 1) } };
+			let seven = 7;
+			let isOdd = fn(n){ if(n == 0){ false } else { isEven(n - 1) } };
+			[isEven(seven), isOdd(seven)];
+		};
+		wrapper();`, []any{false, true}},
+		{`let wrapper = fn(){
+			let f = fn(){ 1 };
+			let first = f();
+			let f = fn(){ 2 };
+			[first, f()];
+		};
+		wrapper();`, []any{1, 2}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStackOverflow(t *testing.T) {
+	tests := []vmTestCase{
+		{`let f = fn(){ f() }; f()`, "stack overflow"},
+		{`let f = fn(n){ f(n + 1) }; f(0)`, "stack overflow"},
+		{`let f = fn(){ let a = 1; let b = 2; let c = 3; let d = 4; f() }; f()`, "stack overflow"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{`while(false){ 10 }; 5`, 5},
+		{`while(true){ break; }; 5`, 5},
+		{`while(true){ 1; 2; if(true){ break; } }; 3`, 3},
+		{`while(true){ if(false){ 1 } else { if(true){ break; } } }; 4`, 4},
+		{`for(let i = 0; i < 0; i){ 10 }; i`, 0},
+		{`for(;;){ break; }; 6`, 6},
+		{`for(let i = 7; true; i){ if(i == 7){ break; } else { continue; } }; i`, 7},
+		{`let f = fn(){ while(true){ break; }; 7 }; f();`, 7},
+		{`let f = fn(){ for(let i = 1; true; i){ return i + 1; } }; f();`, 2},
+		{`let f = fn(){ let a = 1; while(true){ let b = 2; if(true){ break; } }; a + b }; f();`, 3},
+		{`let f = fn(n){ while(true){ while(true){ break; }; break; }; n }; [f(1), f(2)][1];`, 2},
+		{`let f = fn(){ while(true){ break; } }; f();`, Null},
+		{`let i = 0; let sum = 0; while(i < 10){ i += 1; sum += i; }; sum`, 55},
+		{`let sum = 0; for(let i = 0; i < 5; i += 1){ if(i == 3){ continue; } sum += i; }; sum`, 7},
+		{`let sum = 0; for(let i = 0; true; i += 1){ if(i > 4){ break; } sum += i; }; sum`, 10},
+		{`let count = fn(n){ let c = 0; while(true){ if(c == n){ break; } c += 1; } c }; count(42);`, 42},
+		{`let grid = fn(n){
+			let total = 0;
+			for(let i = 0; i < n; i += 1){
+				for(let j = 0; j < n; j += 1){
+					if(j > i){ break; }
+					total += 1;
+				}
+			}
+			total
+		};
+		grid(4);`, 10},
+		{`let f = fn(){ let i = 0; while(i < 100000){ i += 1; } i }; f();`, 100000},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a = 1; a = 2; a`, 2},
+		{`let a = 1; a = 2`, 2},
+		{`let a = 1; let b = 2; a = b = 5; a + b`, 10},
+		{`let a = 10; a += 5; a`, 15},
+		{`let a = 10; a -= 5; a`, 5},
+		{`let a = 10; a *= 5; a`, 50},
+		{`let a = 10; a /= 5; a`, 2},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{`let f = fn(x){ x += 1; x *= 2; x }; f(3);`, 8},
+		{`let g = 1; let f = fn(){ g = g + 1; }; f(); f(); g`, 3},
+		{`let f = fn(){ let a = 1; let b = a; a = 5; b }; f();`, 1},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexAssignments(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a = [1, 2, 3]; a[0] = 5; a`, []int{5, 2, 3}},
+		{`let a = [1, 2, 3]; a[2] = 9`, 9},
+		{`let a = [1, 2, 3]; a[1] += 10; a[1]`, 12},
+		{`let a = [[1], [2]]; a[1][0] = 7; a[1]`, []int{7}},
+		{`let a = [1, 2]; let b = a; b[0] = 3; a`, []int{3, 2}},
+		{`let set = fn(arr){ arr[0] = 100; }; let a = [1]; set(a); a[0]`, 100},
+		{`let a = [1]; let b = push(a, 2); b[0] = 5; a`, []int{1}},
+		{`let h = {}; h["one"] = 1; h["one"]`, 1},
+		{`let h = {"one": 1}; h["one"] *= 4; h["one"]`, 4},
+		{`let h = {1: 1}; h[2] = 2; h`, map[object.HashKey]int64{
+			(&object.Integer{Value: 1}).HashKey(): 1,
+			(&object.Integer{Value: 2}).HashKey(): 2,
+		}},
+		{`let a = [0, 0, 0]; for(let i = 0; i < 3; i += 1){ a[i] = i * i; }; a`, []int{0, 1, 4}},
+		{`let f = fn(){ let counts = {}; counts["x"] = 0; let i = 0; while(i < 5){ counts["x"] += 1; i += 1; } counts["x"] }; f();`, 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexAssignmentErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a = [1, 2, 3]; a[3] = 1;`, "index out of range: 3 for array of length 3"},
+		{`let a = []; a[-1] = 1;`, "index out of range: -1 for array of length 0"},
+		{`let a = [1, 2]; a[-3] = 1;`, "index out of range: -3 for array of length 2"},
+		{`let a = [1]; a["x"] = 1;`, "array index must be an integer, got STRING"},
+		{`let h = {}; h[[1]] = 1;`, "unhashable type ARRAY"},
+		{`let s = "abc"; s[0] = "x";`, "index assignment not supported: STRING"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{`true && true`, true},
+		{`true && false`, false},
+		{`false && true`, false},
+		{`false || true`, true},
+		{`false || false`, false},
+		{`1 && 2`, 2},
+		{`(if(false){1}) && 2`, Null},
+		{`false || 5`, 5},
+		{`3 || 5`, 3},
+		{`"a" && "b"`, "b"},
+		{`1 < 2 && 2 < 3`, true},
+		{`1 > 2 || 2 > 3`, false},
+		{`false || (if(false){1}) || 7`, 7},
+		{`if(1 < 2 && !false){ 10 } else { 20 }`, 10},
+		{`let n = 0; let bump = fn(){ n += 1; true }; false && bump(); n`, 0},
+		{`let n = 0; let bump = fn(){ n += 1; true }; true || bump(); n`, 0},
+		{`let n = 0; let bump = fn(){ n += 1; true }; true && bump(); n`, 1},
+		{`let n = 0; let bump = fn(){ n += 1; false }; bump() || bump() || bump(); n`, 3},
+		{`let a = [1]; let safe = fn(i){ i < len(a) && a[i] > 0 }; [safe(0), safe(5)][1]`, false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"1.5 + 2.25", 3.75},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"7 / 2", 3},
+		{"-2.5", -2.5},
+		{"5.5 % 2", 1.5},
+		{"2 ** 0.5 * 2 ** 0.5 > 1.99", true},
+		{"2.0 ** -1", 0.5},
+		{"1e3", 1000.0},
+		{"1 == 1.0", true},
+		{"1 < 1.5", true},
+		{"2.5 >= 3", false},
+		{"0.1 + 0.2 != 0.3", true},
+		{`let h = {1: "one"}; h[1.0]`, "one"},
+		{`let h = {1.5: "x"}; h[1.5]`, "x"},
+		{"int(3.9)", 3},
+		{"int(-3.9)", -3},
+		{`int("42")`, 42},
+		{"int(-9223372036854775807.0 - 1024)", -9223372036854775807 - 1},
+		{"int(1e19)", &object.Error{Message: "could not convert 1e+19 to an int"}},
+		{"int(-1e19)", &object.Error{Message: "could not convert -1e+19 to an int"}},
+		{"int(1e308 * 10)", &object.Error{Message: "could not convert +Inf to an int"}},
+		{"int(-1e308 * 10)", &object.Error{Message: "could not convert -Inf to an int"}},
+		{"int(1e308 * 10 - 1e308 * 10)", &object.Error{Message: "could not convert NaN to an int"}},
+		{"float(2)", 2.0},
+		{`float("2.5")`, 2.5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []vmTestCase{
+		{`"say \"hi\""`, `say "hi"`},
+		{`len("a\nb")`, 3},
+		{`len("\u{1F600}")`, 4},
+		{"`line one\nline two`", "line one\nline two"},
+		{"`C:\\path` == \"C:\\\\path\"", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestOptionalSemicolons(t *testing.T) {
+	tests := []vmTestCase{
+		{"let a = 1\nlet b = 2\na + b", 3},
+		{"let total = 0\nfor (let i = 0; i < 4; i += 1) {\n\ttotal += i\n}\ntotal", 6},
+		{"let f = fn(x) {\n\tif (x > 1) {\n\t\treturn\n\t}\n\tx\n}\nf(1)", 1},
+		{"let n = 1\n\t- 2\nn", 1},
+		{"let n = 1 +\n\t2\nn", 3},
+		{"let x = 1\n; x + 1", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBareReturn(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = fn(){ return; 1 }; f()", Null},
+		{"let f = fn(x){ if(x){ return } x }; f(false)", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIdentifiersAndNumberLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"let snake_case = 0xFF; snake_case", 255},
+		{"let _hidden = 0b1010; _hidden + 0o10", 18},
+		{"let größe = 1_000_000; größe / 1_000", 1000},
+		{"let 名前 = \"monkey\"; 名前", "monkey"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestComments(t *testing.T) {
+	tests := []vmTestCase{
+		{"// nothing but a note\n1 + 2", 3},
+		{"let a = 6; /* a /* nested */ comment */ a / 2 // halved", 3},
+		{"let a = 6;\na /= 2; // compound division still works\na", 3},
+		{`"// not a comment"`, "// not a comment"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringComparisons(t *testing.T) {
+	tests := []vmTestCase{
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"apple" < "banana"`, true},
+		{`"app" < "apple"`, true},
+		{`"B" < "a"`, true},
+		{`"a" <= "a"`, true},
+		{`"b" >= "a"`, true},
+		{`"b" > "ba"`, false},
+		{`"" < "a"`, true},
+		{`"mon" + "key" == "monkey"`, true},
+		{`"monkey" != "monkey"`, false},
+		{`let min = fn(a, b){ if(a <= b){ a } else { b } }; min("pear", "peach")`, "peach"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`match (1) { 1 => 10, _ => 20 }`, 10},
+		{`match (2) { 1 => 10, _ => 20 }`, 20},
+		{`match (-1) { -1 => "minus", _ => "other" }`, "minus"},
+		{`match (1.0) { 1 => "one", _ => "other" }`, "one"},
+		{`match ("a") { 1 => 1, "a" => 2 }`, 2},
+		{`match (true) { false => 0, true => 1 }`, 1},
+		{`match (5) { x => x * 2 }`, 10},
+		{`match ([1, 2, 3]) { [a, b] => 0, [a, _, c] => a + c }`, 4},
+		{`match ([1, 2, 3, 4]) { [first, ...rest] => rest }`, []int{2, 3, 4}},
+		{`match ([1]) { [first, ...rest] => len(rest) }`, 0},
+		{`match ([]) { [first, ..._] => 1, [] => 2 }`, 2},
+		{`match ("abc") { [a] => 1, _ => 2 }`, 2},
+		{`match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }`, 6},
+		{`match ({"type": "user", "id": 7}) { {"type": "bot"} => 0, {"type": "user", "id": id} => id }`, 7},
+		{`match ({"id": 7}) { {"type": _} => 0, {} => 1 }`, 1},
+		{`match ([1]) { {} => 0, _ => 1 }`, 1},
+		{`match (150) { x if x > 100 => "big", x if x > 10 => "medium", _ => "small" }`, "big"},
+		{`match (50) { x if x > 100 => "big", x if x > 10 => "medium", _ => "small" }`, "medium"},
+		{`match ([3, 4]) { [a, b] if a > b => a, [a, b] => b }`, 4},
+		{"let f = fn(v) {\n match (v) {\n 0 => \"zero\"\n [x, ...r] => x\n _ => \"other\"\n }\n}\n[f(0), f([5, 6]), f(\"s\")]", []any{"zero", 5, "other"}},
+		{`let f = fn(x) { match (x) { n if n > 0 => fn() { n * 2 } } }; f(4)()`, 8},
+		{`match (1) { 1 => match (2) { 2 => "inner" } }`, "inner"},
+		{`1 + match (1) { 1 => 2 }`, 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMatchBindingScope(t *testing.T) {
+	tests := []vmTestCase{
+		{`let q = 10; match (5) { q => q }; q`, 10},
+		{`let q = 10; match (5) { q => q }`, 5},
+		{`let x = 10; match (5) { [x] => 1, _ => 2 }; x + 1`, 11},
+		{`let f = fn(){ let z = 10; match (5) { [z] => 1, _ => 2 }; z + 1 }; f()`, 11},
+		{`let a = 1; let b = 2; match ([1, 2]) { [a, b] if a > 5 => 0, _ => 9 }; [a, b]`, []any{1, 2}},
+		{`let x = 10; match ([1, 2]) { [x, 3] => x, [_, x] => x * 100 }`, 200},
+		{`let x = 10; match ({"k": 1}) { {"k": x, "j": y} => 0, {"k": y} => x + y }`, 11},
+		{`let f = fn(v){ let x = "outer"; [match (v) { [x] => x, _ => x }, x] }; [f([1]), f(2)]`, []any{[]any{1, "outer"}, []any{"outer", "outer"}}},
+		{`let g = match (3) { n => fn(){ n * 2 } }; g()`, 6},
+		{`match ([1, [2, 3]]) { [x, [y, x]] => x, _ => 0 }`, 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`match (3) { 1 => 1, 2 => 2 }`, "no match arm for value 3"},
+		{`match ([1, 2]) { [a] => a, x if false => x }`, "no match arm for value [1, 2]"},
+		{`let f = fn(x) { match (x) { "a" => 1 } }; f("b")`, "no match arm for value b"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []vmTestCase{
+		{`let [a, b] = [1, 2]; a + b`, 3},
+		{`let [a, b, ...rest] = [1, 2, 3, 4]; rest`, []int{3, 4}},
+		{`let [a, ...rest] = []; [a, rest]`, []any{Null, []int{}}},
+		{`let [a, _, c] = [1, 2, 3]; a + c`, 4},
+		{`let [a, b = 5, c = 6] = [1, 2]; [a, b, c]`, []int{1, 2, 6}},
+		{`let {"name": n, "age": age = 30} = {"name": "ann"}; [n, age]`, []any{"ann", 30}},
+		{`let [[a, b], {"k": v}] = [[1, 2], {"k": 3}]; a + b + v`, 6},
+		{`let f = fn(p) { let [h, ...t] = p; [h, t] }; f([1, 2, 3])`, []any{1, []int{2, 3}}},
+		{`let [x = 1 + 1] = [false]; x`, false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestParallelAssignment(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a = 1; let b = 2; a, b = b, a; [a, b]`, []int{2, 1}},
+		{`let a = 1; let b = 2; let c = 3; a, b, c = c, a, b; [a, b, c]`, []int{3, 1, 2}},
+		{`let s = [1, 2, 3]; s[0], s[2] = s[2], s[0]; s`, []int{3, 2, 1}},
+		{`let f = fn() { let a = 1; let b = 2; a, b = b, a + b; [a, b] }; f()`, []int{2, 3}},
+		{"let a = 0; let b = 1\nlet i = 0\nwhile (i < 10) {\n a, b = b, a + b\n i += 1\n}\na", 55},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`let [a] = 5;`, "index operator not supported: INTEGER INTEGER"},
+		{`let [a, ...r] = {"a": 1};`, "rest element not supported: HASHPAIR"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestDefaultParameters(t *testing.T) {
+	tests := []vmTestCase{
+		{`let f = fn(x, y = 10) { x + y }; f(1)`, 11},
+		{`let f = fn(x, y = 10) { x + y }; f(1, 2)`, 3},
+		{`let f = fn(x = 1, y = x + 1) { [x, y] }; f()`, []int{1, 2}},
+		{`let f = fn(x = 1, y = x + 1) { [x, y] }; f(5)`, []int{5, 6}},
+		{`let f = fn(x = 1) { x }; f(if (false) { 2 })`, 1},
+		{`let f = fn(x = 1) { x }; f(false)`, false},
+		{`let base = 100; let f = fn(x = base) { x }; f()`, 100},
+		{`let f = fn(n, acc = 0) { if (n == 0) { return acc }; f(n - 1, acc + n) }; f(4)`, 10},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestVariadicFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`let f = fn(first, ...others) { others }; f(1)`, []int{}},
+		{`let f = fn(first, ...others) { others }; f(1, 2, 3)`, []int{2, 3}},
+		{`let f = fn(...all) { len(all) }; f()`, 0},
+		{`let f = fn(...all) { len(all) }; f(1, 2, 3, 4)`, 4},
+		{`let f = fn(a, b = 2, ...r) { [a, b, r] }; f(1)`, []any{1, 2, []int{}}},
+		{`let f = fn(a, b = 2, ...r) { [a, b, r] }; f(1, 5, 6, 7)`, []any{1, 5, []int{6, 7}}},
+		{`let f = fn(...r) { let g = fn() { r }; g() }; f(1, 2)`, []int{1, 2}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSpread(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a = [1, 2]; let b = [3]; [...a, ...b]`, []int{1, 2, 3}},
+		{`let a = [1, 2]; let b = [3]; [0, ...a, 9, ...b, 10]`, []int{0, 1, 2, 9, 3, 10}},
+		{`[...[]]`, []int{}},
+		{`let a = [1]; let b = [...a]; b[0] = 2; a`, []int{1}},
+		{`let f = fn(x, y) { x - y }; let args = [5, 2]; f(...args)`, 3},
+		{`let f = fn(...xs) { xs }; f(...[1, 2], 3, ...[4])`, []int{1, 2, 3, 4}},
+		{`let f = fn(x, y = 10) { x + y }; f(...[1])`, 11},
+		{`len(...[[1, 2, 3]])`, 3},
+		{`push(...[[1], 2])`, []int{1, 2}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSpreadErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`[...1]`, "cannot spread INTEGER"},
+		{`let f = fn(x) { x }; f(...5)`, "cannot spread INTEGER"},
+		{`let f = fn(x) { x }; f(...[1, 2])`, "wrong number of arguments: want=1, got=2"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestValueEquality(t *testing.T) {
+	tests := []vmTestCase{
+		{`"a" + "b" == "ab"`, true},
+		{`"a" + "b" != "ab"`, false},
+		{`[1] == [1]`, true},
+		{`[1, 2] == [1, 3]`, false},
+		{`[1, 2] == [1, 2, 3]`, false},
+		{`[1, [2, "x"]] == [1, [2, "x"]]`, true},
+		{`[1, [2, "x"]] != [1, [2, "y"]]`, true},
+		{`[1] == [1.0]`, true},
+		{`[] == []`, true},
+		{`[true, false] == [true, false]`, true},
+		{`[1] == {1: 1}`, false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{} == {}`, true},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+		{`len == len`, true},
+		{`1 == "1"`, false},
+		{`[1] == 1`, false},
+		{`let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b`, true},
+		{`let a = [1, 2]; a[0] = a; let b = [1, 3]; b[0] = b; a == b`, false},
+		{`let h = {"self": 1}; h["self"] = h; let g = {"self": 1}; g["self"] = g; h == g`, true},
+		{`let a = [1]; a[0] = a; a == [a]`, true},
+		{`let x = "a" + "b"; match (x) { "ab" => 1, _ => 2 }`, 1},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestComparisonErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`1 < "a"`, "unsupported types for comparison: INTEGER STRING"},
+		{`true >= false`, "unsupported types for comparison: BOOLEAN BOOLEAN"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`1 << -1`, "negative shift count: -1"},
+		{`let n = -3; 16 >> n`, "negative shift count: -3"},
+		{`5 % 0`, "modulo by zero"},
+		{`1 / 0`, "division by zero"},
+		{`let a = 10; a /= 0`, "division by zero"},
+		{`2 ** -1`, "negative exponent for integer power: -1"},
+		{`~"a"`, "unsupported type for bitwise not operation STRING"},
+		{`true & 1`, "unsupported types for binary operation: BOOLEAN INTEGER"},
+		{`1.5 & 1`, "unsupported types for binary operation: FLOAT INTEGER"},
+	}
+
+	runVmErrorTests(t, tests)
+}
+
+func TestIntegerOverflowWrap(t *testing.T) {
+	tests := []vmTestCase{
+		{"9223372036854775807 + 1", math.MinInt64},
+		{"-9223372036854775807 - 2", math.MaxInt64},
+		{"4611686018427387904 * 2", math.MinInt64},
+		{"(-9223372036854775807 - 1) / -1", math.MinInt64},
+		{"-(-9223372036854775807 - 1)", math.MinInt64},
+		{"2 ** 64", 0},
+		{"1 << 64", 0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIntegerOverflowError(t *testing.T) {
+	tests := []vmTestCase{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"(-9223372036854775807 - 1) / -1", "integer overflow: -9223372036854775808 / -1"},
+		{"-(-9223372036854775807 - 1)", "integer overflow: -(-9223372036854775808)"},
+		{"3 ** 40", "integer overflow: 3 ** 40"},
+		{"1 << 63", "integer overflow: 1 << 63"},
+	}
+
+	runVmErrorTestsWithPolicy(t, tests, OverflowError)
+
+	// results that fit are unaffected by the policy
+	runVmTestsWithPolicy(t, []vmTestCase{
+		{"9223372036854775806 + 1", math.MaxInt64},
+		{"-1 << 63", math.MinInt64},
+		{"3 ** 39", 4052555153018976267},
+		{"-9223372036854775807 - 1", math.MinInt64},
+	}, OverflowError)
+}
+
+func TestIntegerOverflowBig(t *testing.T) {
+	tests := []vmTestCase{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"2 ** 100", "1267650600228229401496703205376"},
+		{"1 << 70", "1180591620717411303424"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"let n = 2 ** 64; n * n", "340282366920938463463374607431768211456"},
+		{"let n = 2 ** 64; -n", "-18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetOverflowPolicy(OverflowBig)
+		if err := vm.Run(); err != nil {
+			t.Fatalf("failed to run vm: %s", err)
+		}
+
+		result, ok := vm.LastPoppedStackElement().(*object.BigInteger)
+		if !ok {
+			t.Fatalf("object is not a BigInteger, got=%T", vm.LastPoppedStackElement())
+		}
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("wrong value for %q, want=%s, got=%s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	// values that come back into range are plain integers again
+	runVmTestsWithPolicy(t, []vmTestCase{
+		{"(9223372036854775807 + 1) - 1", math.MaxInt64},
+		{"2 ** 64 / 2 ** 60", 16},
+		{"(2 ** 64) % 7", 2},
+		{"(1 << 70) >> 68", 4},
+		{"2 ** 64 > 9223372036854775807", true},
+		{"2 ** 64 == 2 ** 64", true},
+		{"2 ** 64 == 1", false},
+		{`let h = {}; h[2 ** 64] = "big"; h[2 ** 64]`, "big"},
+		{"2 ** 64 * 0.5", 9223372036854775808.0},
+	}, OverflowBig)
+
+	runVmErrorTestsWithPolicy(t, []vmTestCase{
+		{"2 ** 64 / 0", "division by zero"},
+		{"2 ** 64 % 0", "modulo by zero"},
+	}, OverflowBig)
+}
+
+func TestModules(t *testing.T) {
+	dir := filepath.Join("..", "testdata", "modules")
+
+	tests := []vmTestCase{
+		{`import "lib/math.dm" as m; m.square(4)`, 16},
+		{`import "lib/math.dm" as m; let f = fn(x) { m.square(x) + 1 }; f(3)`, 10},
+		{`import "lib/math.dm" as m; m.twice(5)`, 10},
+		{`import "lib/math.dm" as m; [m.even(10), m.even(7)]`, []any{true, false}},
+		{`import "lib/math.dm" as m; let square = 1; m.square(2) + square`, 5},
+		{`import "lib/math.dm" as m; import "lib/math.dm" as again; m.square(2); again.square(3); m.count()`, 2},
+		{`import "counter.dm" as a; import "counter.dm" as b; [a.n, b.n]`, []any{1, 1}},
+		{`import "lib/util.dm" as u; import "lib/math.dm" as m; [u.double(1), m.twice(2)]`, []any{2, 4}},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		comp.UseModules(compiler.NewModules(dir))
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("failed to compile: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("failed to run vm: %s", err)
+		}
+
+		testExpectedObject(t, tt.expected, vm.LastPoppedStackElement())
+	}
+}